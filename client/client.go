@@ -1,31 +1,28 @@
-// This is an example websocket client from the docs at:
-// http://godoc.org/code.google.com/p/go.net/websocket#example-Dial
+// This is an example client of gitsyncd's event stream, printing the changes
+// announced by peers. See the gitsync/client package to write your own.
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"log"
-
-	"golang.org/x/net/websocket"
+	"github.com/raybejjani/gitsync/gitsync"
+	"github.com/raybejjani/gitsync/gitsync/client"
 )
 
 func main() {
-	origin := "http://localhost/"
-	url := "ws://localhost:12345/events"
-	ws, err := websocket.Dial(url, "", origin)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if _, err := ws.Write([]byte("hello, world!\n")); err != nil {
-		log.Fatal(err)
-	}
+	var (
+		addr   = flag.String("addr", "localhost:12345", "Address of the daemon's web server")
+		filter gitsync.ChangeFilter
+	)
+	flag.StringVar(&filter.User, "user", "", "Only show changes by this user")
+	flag.StringVar(&filter.RepoName, "repo", "", "Only show changes to this repo")
+	flag.StringVar(&filter.RefName, "ref", "", "Only show changes to matching branches, e.g. feature/*")
+	flag.BoolVar(&filter.CheckedOut, "checkedout", false, "Only show changes to checked out branches")
+	flag.Parse()
 
-	for {
-		var msg = make([]byte, 512)
-		var n int
-		if n, err = ws.Read(msg); err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("Received: %s.\n", msg[:n])
+	sub := client.Subscribe(context.Background(), *addr, filter)
+	for change := range sub.Changes() {
+		fmt.Printf("%s@%s %s/%s: %s..%s\n", change.User, change.HostIp, change.RepoName, change.RefName, change.Prev, change.Current)
 	}
 }
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"github.com/raybejjani/gitsync/gitsync/client"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// errUsage is returned for a command line that does not make sense
+var errUsage = errors.New("usage")
+
+// cli runs the gitsync commands
+type cli struct {
+	daemon *client.Client
+	root   string // top directory of the repository
+	json   bool   // print the daemon's replies as JSON
+	out    io.Writer
+}
+
+// run runs the command in args
+func (c *cli) run(args []string) error {
+	switch cmd, args := args[0], args[1:]; {
+	case cmd == "status" && len(args) == 0:
+		return c.status()
+	case cmd == "peers" && len(args) == 0:
+		return c.peers()
+	case cmd == "branches" && len(args) == 0:
+		return c.branches()
+	case cmd == "log":
+		return c.log(args)
+	case cmd == "fetch" && len(args) == 1:
+		return c.fetch(args[0])
+	case cmd == "checkout" && len(args) == 1:
+		return c.checkout(args[0])
+	case cmd == "diff" && len(args) >= 1:
+		return c.diff(args[0], args[1:])
+	case cmd == "prune" && len(args) == 0:
+		return c.prune()
+	}
+	return errUsage
+}
+
+// ago formats how long ago t was
+func ago(t time.Time) string {
+	return time.Since(t).Round(time.Second).String() + " ago"
+}
+
+// short abbreviates a commit hash
+func short(commit string) string {
+	if len(commit) > 10 {
+		return commit[:10]
+	}
+	return commit
+}
+
+func (c *cli) status() error {
+	status, err := c.daemon.Status()
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(c.out, status)
+	}
+
+	fmt.Fprintf(c.out, "gitsyncd is watching %s as %s (peer %s), up for %s\n",
+		status.Path, status.User, gitsync.ShortPeerID(status.PeerID), time.Since(status.Started).Round(time.Second))
+	fmt.Fprintf(c.out, "%d peer(s) heard from\n\n", status.Peers)
+
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TRANSPORT\tSTATE\tDETAIL")
+	for _, t := range status.Transports {
+		state := "down"
+		if t.Up {
+			state = "up"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, state, t.Detail)
+	}
+	return w.Flush()
+}
+
+func (c *cli) peers() error {
+	peers, err := c.daemon.Peers()
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(c.out, peers)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tPEER\tHOST\tLAST SEEN\tKEY")
+	for _, p := range peers {
+		key := "approved"
+		if p.KeyMismatch {
+			key = "not approved, see 'gitsyncd keys'"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.User, gitsync.ShortPeerID(p.PeerID), p.HostIp, ago(p.LastSeen), key)
+	}
+	return w.Flush()
+}
+
+// spec names the peer's branch m mirrors as <user>-<peer id>/<branch>, or
+// returns the mirror branch itself if it was not announced since the daemon
+// started
+func spec(m gitsync.Mirror) string {
+	if m.Change == nil {
+		return m.Branch
+	}
+	return fmt.Sprintf("%s-%s/%s", m.Change.User, gitsync.ShortPeerID(m.Change.PeerID), m.Change.RefName)
+}
+
+func (c *cli) branches() error {
+	mirrors, err := c.daemon.Branches()
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(c.out, mirrors)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PEER BRANCH\tLOCAL BRANCH\tCOMMIT\tSTATE")
+	for _, m := range mirrors {
+		state := "fetched"
+		switch {
+		case m.FetchError != "":
+			state = "fetch failed: " + m.FetchError
+		case m.Current == "":
+			state = "not fetched"
+		case m.Change != nil && m.Change.Current != m.Current:
+			state = "behind, fetch to update"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", spec(m), m.Branch, short(m.Current), state)
+	}
+	return w.Flush()
+}
+
+// isShortPeerID reports whether s looks like the output of ShortPeerID
+func isShortPeerID(s string) bool {
+	if len(s) != len(gitsync.ShortPeerID("0000000000000000")) {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve finds the mirror branch of the peer's branch named by s, given as
+// <user>/<branch>, <user>-<peer id>/<branch> or as the mirror branch itself
+func resolve(mirrors []gitsync.Mirror, s string) (gitsync.Mirror, error) {
+	for _, m := range mirrors {
+		if m.Branch == s {
+			return m, nil
+		}
+	}
+
+	slash := strings.Index(s, "/")
+	if slash <= 0 || slash == len(s)-1 {
+		return gitsync.Mirror{}, fmt.Errorf("%s is not of the form <user>/<branch>", s)
+	}
+	user, ref := s[:slash], s[slash+1:]
+
+	// mirror branches announced since the daemon started are known by their
+	// announcement. The others are recognised by their name, if they follow
+	// the default naming of gitsync-<user>-<peer id>-<branch>.
+	var found []gitsync.Mirror
+	for _, m := range mirrors {
+		if c := m.Change; c != nil {
+			if c.RefName == ref && (c.User == user || c.User+"-"+gitsync.ShortPeerID(c.PeerID) == user) {
+				found = append(found, m)
+			}
+			continue
+		}
+		rest := strings.TrimPrefix(m.Branch, gitsync.DefaultMirrorPrefix+user+"-")
+		if rest == m.Branch {
+			continue
+		}
+		if rest == ref || (strings.HasSuffix(rest, "-"+ref) && isShortPeerID(strings.TrimSuffix(rest, "-"+ref))) {
+			found = append(found, m)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return gitsync.Mirror{}, fmt.Errorf("no peer's branch matches %s, see 'gitsync branches'", s)
+	case 1:
+		return found[0], nil
+	}
+	var names []string
+	for _, m := range found {
+		names = append(names, spec(m))
+	}
+	return gitsync.Mirror{}, fmt.Errorf("%s is ambiguous, it could be any of %s", s, strings.Join(names, ", "))
+}
+
+func (c *cli) log(args []string) error {
+	flags := flag.NewFlagSet("log", flag.ContinueOnError)
+	n := flags.Int("n", 20, "Number of events to show, 0 for all those the daemon keeps")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	events, err := c.daemon.Events(*n)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(c.out, events)
+	}
+
+	for _, e := range events {
+		fmt.Fprintf(c.out, "%s  %-11s  %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Kind, e.Message)
+	}
+	return nil
+}
+
+func (c *cli) fetch(s string) error {
+	mirrors, err := c.daemon.Branches()
+	if err != nil {
+		return err
+	}
+	m, err := resolve(mirrors, s)
+	if err != nil {
+		return err
+	}
+
+	results, err := c.daemon.Fetch(m.Branch)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(c.out, results)
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			return fmt.Errorf("cannot fetch %s: %s", r.Branch, r.Error)
+		}
+		fmt.Fprintf(c.out, "Fetched %s into %s\n", spec(m), r.Branch)
+	}
+	return nil
+}
+
+// fetched resolves s to a mirror branch that has been fetched
+func (c *cli) fetched(s string) (gitsync.Mirror, error) {
+	mirrors, err := c.daemon.Branches()
+	if err != nil {
+		return gitsync.Mirror{}, err
+	}
+	m, err := resolve(mirrors, s)
+	if err != nil {
+		return m, err
+	}
+	if m.Current == "" {
+		return m, fmt.Errorf("%s has not been fetched yet, try 'gitsync fetch %s'", s, s)
+	}
+	return m, nil
+}
+
+// checkout checks out the peer's branch without creating a branch of our own,
+// so nothing is committed on the mirror branch, which the daemon overwrites
+func (c *cli) checkout(s string) error {
+	m, err := c.fetched(s)
+	if err != nil {
+		return err
+	}
+	return git(c.root, "checkout", "--detach", m.Branch)
+}
+
+// diff shows the changes on the peer's branch since it forked from ours
+func (c *cli) diff(s string, gitArgs []string) error {
+	m, err := c.fetched(s)
+	if err != nil {
+		return err
+	}
+	return git(c.root, append(append([]string{"diff"}, gitArgs...), "HEAD..."+m.Branch)...)
+}
+
+func (c *cli) prune() error {
+	if err := c.daemon.Cleanup(); err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(c.out, struct{}{})
+	}
+	fmt.Fprintln(c.out, "Deleted the gitsync branches")
+	return nil
+}
@@ -0,0 +1,111 @@
+// gitsync talks to the gitsyncd watching a repository, to see what teammates
+// are working on and to fetch, check out or diff their branches.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"github.com/raybejjani/gitsync/gitsync/client"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const usage = `usage: gitsync [flags] <command>
+
+commands:
+  status                     show the daemon and the state of its transports
+  peers                      list the peers heard from
+  branches                   list the peers' branches mirrored here
+  log [-n count]             show recent activity
+  fetch <user>/<branch>      fetch a peer's branch again
+  checkout <user>/<branch>   check out a peer's branch, detached
+  diff <user>/<branch> [git diff flags]
+                             show what a peer's branch changes
+  prune                      delete the mirrored branches
+
+When several peers share a user name, give a branch as
+<user>-<peer id>/<branch>, as listed by 'gitsync branches'.
+
+flags:`
+
+// repoRoot returns the top directory of the repository dir is in
+func repoRoot(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// git runs git in dir, attached to our terminal
+func git(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// printJSON writes v as indented JSON, for --json
+func printJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func main() {
+	var (
+		asJSON = flag.Bool("json", false, "Print the daemon's replies as JSON")
+		dir    = flag.String("C", ".", "Directory in the repository to use")
+		socket = flag.String("socket", "", "Control socket of the daemon. Defaults to the one gitsyncd uses for the repository")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// -json may also follow the command, as in 'gitsync peers --json'
+	var args []string
+	for _, arg := range flag.Args() {
+		if arg == "-json" || arg == "--json" {
+			*asJSON = true
+		} else {
+			args = append(args, arg)
+		}
+	}
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	root, err := repoRoot(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gitsync: %s\n", err)
+		os.Exit(1)
+	}
+	if *socket == "" {
+		*socket = gitsync.ControlSocketPath(root)
+	}
+
+	cli := &cli{
+		daemon: client.New(*socket),
+		root:   root,
+		json:   *asJSON,
+		out:    os.Stdout}
+	if err = cli.run(args); err == errUsage {
+		flag.Usage()
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "gitsync: %s\n", err)
+		os.Exit(1)
+	}
+}
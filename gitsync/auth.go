@@ -0,0 +1,219 @@
+package gitsync
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultReplayWindow is how far a message's timestamp may be from our clock
+// before it is rejected. Nonces are remembered for this long.
+const DefaultReplayWindow = 30 * time.Second
+
+const nonceSize = 12 // the standard AES-GCM nonce size
+
+// authMessage is the wire format of a message authenticated with a shared
+// secret. When Encrypted is set, Payload is sealed with AES-GCM and MAC is
+// empty. Otherwise the MAC covers the timestamp, nonce and payload.
+type authMessage struct {
+	Timestamp int64  // sender's clock in unix nanoseconds
+	Nonce     []byte // random, unique per message
+	Encrypted bool   // Payload is encrypted
+	Payload   []byte // gob encoded GitChange
+	MAC       []byte // HMAC-SHA256 keyed with the shared secret
+}
+
+// Authenticator signs or encrypts outgoing messages with a secret shared by
+// the team, and verifies incoming ones. Messages with a bad MAC or
+// ciphertext, a timestamp outside the replay window, or a nonce already seen
+// within the window are rejected.
+// To allow keys to be rotated, an Authenticator holds a set of keys. The first
+// is used to seal messages and any of them is accepted when opening.
+type Authenticator struct {
+	encrypt bool
+	window  time.Duration
+
+	keysLock sync.RWMutex // lock keys and aeads, which SetKeys replaces
+	keys     [][]byte
+	aeads    []cipher.AEAD // one per key
+
+	sync.Mutex                      // lock seen
+	seen       map[string]time.Time // nonces seen, with their message time
+}
+
+// NewAuthenticator returns an Authenticator using keys, the first of which is
+// used to seal messages. If encrypt is set, messages are encrypted as well as
+// authenticated. Messages older or newer than window are rejected as possible
+// replays.
+func NewAuthenticator(keys [][]byte, encrypt bool, window time.Duration) (*Authenticator, error) {
+	a := &Authenticator{
+		encrypt: encrypt,
+		window:  window,
+		seen:    make(map[string]time.Time)}
+
+	if err := a.SetKeys(keys); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetKeys replaces the keys, e.g. when the secret file is edited while the
+// daemon runs. The first is used to seal messages from then on.
+func (a *Authenticator) SetKeys(keys [][]byte) error {
+	if len(keys) == 0 {
+		return errors.New("no shared secret")
+	}
+	var aeads []cipher.AEAD
+	for _, key := range keys {
+		if len(key) == 0 {
+			return errors.New("empty shared secret")
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return err
+		}
+		aeads = append(aeads, aead)
+	}
+
+	a.keysLock.Lock()
+	defer a.keysLock.Unlock()
+	a.keys, a.aeads = keys, aeads
+	return nil
+}
+
+// newAEAD builds an AES-256-GCM cipher from a team key of any length
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Key returns the key used to seal messages
+func (a *Authenticator) Key() []byte {
+	a.keysLock.RLock()
+	defer a.keysLock.RUnlock()
+	return a.keys[0]
+}
+
+// Keys returns all keys accepted when opening messages
+func (a *Authenticator) Keys() [][]byte {
+	a.keysLock.RLock()
+	defer a.keysLock.RUnlock()
+	return a.keys
+}
+
+// header returns the bytes of msg that are authenticated alongside the
+// payload
+func (msg *authMessage) header() []byte {
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(msg.Timestamp))
+	return append(ts[:], msg.Nonce...)
+}
+
+// mac computes the HMAC of msg with key, ignoring msg.MAC
+func (msg *authMessage) mac(key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(msg.header())
+	h.Write(msg.Payload)
+	return h.Sum(nil)
+}
+
+// Seal wraps payload in an authenticated, and possibly encrypted, message
+// ready to be sent.
+func (a *Authenticator) Seal(payload []byte) ([]byte, error) {
+	msg := authMessage{
+		Timestamp: time.Now().UnixNano(),
+		Nonce:     make([]byte, nonceSize),
+		Encrypted: a.encrypt,
+	}
+	if _, err := rand.Read(msg.Nonce); err != nil {
+		return nil, err
+	}
+
+	a.keysLock.RLock()
+	defer a.keysLock.RUnlock()
+	if msg.Encrypted {
+		msg.Payload = a.aeads[0].Seal(nil, msg.Nonce, payload, msg.header())
+	} else {
+		msg.Payload = payload
+		msg.MAC = msg.mac(a.keys[0])
+	}
+
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open verifies, and decrypts if needed, a message produced by Seal and
+// returns its payload. Encrypted and plain messages are both accepted,
+// regardless of whether a encrypts. An error is returned for any message that
+// should be dropped.
+func (a *Authenticator) Open(data []byte) (payload []byte, err error) {
+	var msg authMessage
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("malformed message: %s", err)
+	}
+
+	if payload, err = a.verify(&msg); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sent := time.Unix(0, msg.Timestamp)
+	if sent.Before(now.Add(-a.window)) || sent.After(now.Add(a.window)) {
+		return nil, fmt.Errorf("timestamp %s outside replay window", sent)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	for nonce, t := range a.seen {
+		if t.Before(now.Add(-a.window)) {
+			delete(a.seen, nonce)
+		}
+	}
+	if _, replayed := a.seen[string(msg.Nonce)]; replayed {
+		return nil, errors.New("replayed nonce")
+	}
+	a.seen[string(msg.Nonce)] = sent
+
+	return payload, nil
+}
+
+// verify checks msg against each of our keys, returning the plaintext payload
+// for the first that matches
+func (a *Authenticator) verify(msg *authMessage) (payload []byte, err error) {
+	a.keysLock.RLock()
+	defer a.keysLock.RUnlock()
+	if msg.Encrypted {
+		if len(msg.Nonce) != nonceSize {
+			return nil, errors.New("bad nonce")
+		}
+		for _, aead := range a.aeads {
+			if payload, err = aead.Open(nil, msg.Nonce, msg.Payload, msg.header()); err == nil {
+				return payload, nil
+			}
+		}
+		return nil, errors.New("cannot decrypt")
+	}
+
+	for _, key := range a.keys {
+		if hmac.Equal(msg.MAC, msg.mac(key)) {
+			return msg.Payload, nil
+		}
+	}
+	return nil, errors.New("bad MAC")
+}
@@ -0,0 +1,127 @@
+package gitsync
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+func newAuthenticator(t *testing.T, encrypt bool, keys ...string) *Authenticator {
+	var raw [][]byte
+	for _, key := range keys {
+		raw = append(raw, []byte(key))
+	}
+	a, err := NewAuthenticator(raw, encrypt, DefaultReplayWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// sealAt seals payload like Seal, but as sent at the given time
+func sealAt(t *testing.T, key string, at time.Time, payload []byte) []byte {
+	msg := authMessage{Timestamp: at.UnixNano(), Nonce: make([]byte, nonceSize), Payload: payload}
+	msg.MAC = msg.mac([]byte(key))
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(msg); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAuthenticator(t *testing.T) {
+	payload := []byte("refs/heads/master moved")
+	for _, test := range []struct {
+		name     string
+		sealer   *Authenticator
+		opener   *Authenticator
+		tamper   func([]byte) []byte
+		wantOpen bool
+	}{
+		{"signed", newAuthenticator(t, false, "team"), newAuthenticator(t, false, "team"), nil, true},
+		{"encrypted", newAuthenticator(t, true, "team"), newAuthenticator(t, true, "team"), nil, true},
+		{"encrypted to a signing peer", newAuthenticator(t, true, "team"), newAuthenticator(t, false, "team"), nil, true},
+		{"signed to an encrypting peer", newAuthenticator(t, false, "team"), newAuthenticator(t, true, "team"), nil, true},
+		{"signed with another key", newAuthenticator(t, false, "other"), newAuthenticator(t, false, "team"), nil, false},
+		{"encrypted with another key", newAuthenticator(t, true, "other"), newAuthenticator(t, true, "team"), nil, false},
+		{"rotated key", newAuthenticator(t, false, "old"), newAuthenticator(t, false, "new", "old"), nil, true},
+		{"rotated encryption key", newAuthenticator(t, true, "old"), newAuthenticator(t, true, "new", "old"), nil, true},
+		{"malformed", newAuthenticator(t, false, "team"), newAuthenticator(t, false, "team"),
+			func(data []byte) []byte { return data[:len(data)/2] }, false},
+		{"signed payload altered", newAuthenticator(t, false, "team"), newAuthenticator(t, false, "team"),
+			func(data []byte) []byte { return bytes.Replace(data, []byte("master"), []byte("evil!!"), 1) }, false},
+		{"encrypted payload altered", newAuthenticator(t, true, "team"), newAuthenticator(t, true, "team"),
+			func(data []byte) []byte {
+				altered := append([]byte(nil), data...)
+				altered[len(altered)-5] ^= 1
+				return altered
+			}, false},
+	} {
+		data, err := test.sealer.Seal(payload)
+		if err != nil {
+			t.Fatalf("%s: Seal: %s", test.name, err)
+		}
+		if test.tamper != nil {
+			data = test.tamper(data)
+		}
+		opened, err := test.opener.Open(data)
+		if !test.wantOpen {
+			if err == nil {
+				t.Errorf("%s: Open succeeded", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Open: %s", test.name, err)
+		} else if !bytes.Equal(opened, payload) {
+			t.Errorf("%s: Open = %q, want %q", test.name, opened, payload)
+		}
+	}
+}
+
+func TestAuthenticatorReplay(t *testing.T) {
+	a := newAuthenticator(t, false, "team")
+	data, err := a.Seal([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Open(data); err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if _, err = a.Open(data); err == nil {
+		t.Errorf("replayed message opened")
+	}
+
+	for _, at := range []time.Time{
+		time.Now().Add(-2 * DefaultReplayWindow),
+		time.Now().Add(2 * DefaultReplayWindow),
+	} {
+		if _, err = a.Open(sealAt(t, "team", at, []byte("payload"))); err == nil {
+			t.Errorf("message sent at %s opened", at)
+		}
+	}
+}
+
+func TestAuthenticatorSetKeys(t *testing.T) {
+	var (
+		sealer = newAuthenticator(t, true, "old")
+		opener = newAuthenticator(t, true, "old")
+	)
+	if err := opener.SetKeys([][]byte{[]byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := sealer.Seal([]byte("payload"))
+	if _, err := opener.Open(data); err == nil {
+		t.Errorf("message sealed with a dropped key opened")
+	}
+
+	for _, keys := range [][][]byte{nil, {[]byte("")}} {
+		if err := opener.SetKeys(keys); err == nil {
+			t.Errorf("SetKeys(%q) succeeded", keys)
+		}
+	}
+	if _, err := NewAuthenticator(nil, false, DefaultReplayWindow); err == nil {
+		t.Errorf("NewAuthenticator without keys succeeded")
+	}
+}
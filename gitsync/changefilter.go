@@ -0,0 +1,60 @@
+package gitsync
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ChangeFilter selects changes by their fields, so subscribers to the event
+// stream only receive those they want. Empty fields match any value.
+type ChangeFilter struct {
+	User       string
+	PeerID     string // the full ID or a prefix, such as ShortPeerID
+	RepoName   string
+	RefName    string // may be a path.Match pattern, e.g. feature/*
+	CheckedOut bool   // only changes to branches checked out by their owner
+}
+
+// Match reports whether change is selected by f
+func (f ChangeFilter) Match(change GitChange) bool {
+	if f.User != "" && f.User != change.User {
+		return false
+	}
+	if f.PeerID != "" && !strings.HasPrefix(change.PeerID, f.PeerID) {
+		return false
+	}
+	if f.RepoName != "" && f.RepoName != change.RepoName {
+		return false
+	}
+	if f.RefName != "" {
+		if ok, _ := path.Match(f.RefName, change.RefName); !ok {
+			return false
+		}
+	}
+	return !f.CheckedOut || change.CheckedOut
+}
+
+// Values encodes f as the query parameters of an events URL
+func (f ChangeFilter) Values() url.Values {
+	q := url.Values{}
+	for key, value := range map[string]string{"user": f.User, "peer": f.PeerID, "repo": f.RepoName, "ref": f.RefName} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if f.CheckedOut {
+		q.Set("checkedout", "true")
+	}
+	return q
+}
+
+// ParseChangeFilter reverses ChangeFilter.Values
+func ParseChangeFilter(q url.Values) ChangeFilter {
+	return ChangeFilter{
+		User:       q.Get("user"),
+		PeerID:     q.Get("peer"),
+		RepoName:   q.Get("repo"),
+		RefName:    q.Get("ref"),
+		CheckedOut: q.Get("checkedout") == "true"}
+}
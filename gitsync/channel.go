@@ -0,0 +1,122 @@
+package gitsync
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"net"
+)
+
+// channelMagic starts every message, ahead of its TTL and channel name
+var channelMagic = []byte("GS\x01")
+
+// maxChannelLen is the longest channel name a message can carry
+const maxChannelLen = 255
+
+// ChannelGroups derives the multicast groups for channel, so that teams on
+// different channels do not even receive each other's messages. The IPv4
+// group is in the organization-local scope, 239.255.0.0/16, and the IPv6 one
+// is link-local like IP6MulticastAddr.
+func ChannelGroups(channel string, port int) (ip4, ip6 *net.UDPAddr) {
+	h := sha256.Sum256([]byte("gitsync channel " + channel))
+	ip4 = &net.UDPAddr{IP: net.IPv4(239, 255, h[0], h[1]), Port: port}
+	ip6 = &net.UDPAddr{
+		IP:   net.IP{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0x67, 0x73, h[2], h[3], h[4], h[5]},
+		Port: port}
+	return ip4, ip6
+}
+
+// addChannel prefixes data with the channel name and ttl, the number of hops
+// the message may still be forwarded by gossiping peers
+func addChannel(channel string, ttl int, data []byte) []byte {
+	b := make([]byte, 0, len(channelMagic)+2+len(channel)+len(data))
+	b = append(b, channelMagic...)
+	b = append(b, byte(ttl), byte(len(channel)))
+	b = append(b, channel...)
+	return append(b, data...)
+}
+
+// splitChannel reverses addChannel
+func splitChannel(data []byte) (channel string, ttl int, payload []byte, err error) {
+	n := len(channelMagic)
+	if len(data) < n+2 || string(data[:n]) != string(channelMagic) {
+		return "", 0, nil, errors.New("not a gitsync message")
+	}
+	ttl, size := int(data[n]), int(data[n+1])
+	if len(data) < n+2+size {
+		return "", 0, nil, errors.New("truncated channel name")
+	}
+	return string(data[n+2 : n+2+size]), ttl, data[n+2+size:], nil
+}
+
+// channelTransport tags the messages sent through a transport with a channel
+// name, and drops those received for other channels before anything else is
+// done with them
+type channelTransport struct {
+	Transport
+	channel string
+	ttl     int // TTL given to our own messages
+}
+
+// withChannel restricts t to channel, sending our messages with ttl
+func withChannel(t Transport, channel string, ttl int) *channelTransport {
+	return &channelTransport{Transport: t, channel: channel, ttl: ttl}
+}
+
+func (t *channelTransport) String() string {
+	if t.channel == "" {
+		return t.Transport.String()
+	}
+	return fmt.Sprintf("%s on channel %q", t.Transport, t.channel)
+}
+
+func (t *channelTransport) Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error {
+	return t.Transport.Send(l, func(hostIp string) ([]byte, error) {
+		data, err := encode(hostIp)
+		if err != nil {
+			return nil, err
+		}
+		return addChannel(t.channel, t.ttl, data), nil
+	})
+}
+
+// forward re-sends a message received through another transport, with ttl.
+// Forwarding is best effort, messages that cannot be delivered are dropped.
+func (t *channelTransport) forward(l log.Logger, data []byte, ttl int) {
+	err := t.Transport.Send(l, func(string) ([]byte, error) {
+		return addChannel(t.channel, ttl, data), nil
+	})
+	if err != nil {
+		l.Debug("Cannot forward through %s: %s", t, err)
+	}
+}
+
+func (t *channelTransport) Receive(l log.Logger, packets chan<- Packet) {
+	raw := make(chan Packet)
+	go func() {
+		t.Transport.Receive(l, raw)
+		close(raw)
+	}()
+
+	for p := range raw {
+		channel, ttl, payload, err := splitChannel(p.Data)
+		if err != nil {
+			l.Debug("Dropping message from %s: %s", p.From, err)
+			continue
+		}
+		if channel != t.channel {
+			l.Fine("Dropping message from %s for channel %q", p.From, channel)
+			continue
+		}
+		p.Data, p.TTL, p.via = payload, ttl, t
+		packets <- p
+	}
+}
+
+// AddPeer passes peers on to the underlying transport, if it keeps a list
+func (t *channelTransport) AddPeer(peer string) {
+	if adder, ok := t.Transport.(PeerAdder); ok {
+		adder.AddPeer(peer)
+	}
+}
@@ -0,0 +1,66 @@
+package gitsync
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChannelFraming(t *testing.T) {
+	for _, test := range []struct {
+		channel string
+		ttl     int
+		payload []byte
+	}{
+		{"", 3, []byte("announcement")},
+		{"team", 0, []byte("announcement")},
+		{"team", 255, nil},
+		{strings.Repeat("c", maxChannelLen), 1, []byte{0, 1, 2}},
+	} {
+		channel, ttl, payload, err := splitChannel(addChannel(test.channel, test.ttl, test.payload))
+		if err != nil {
+			t.Errorf("splitChannel(addChannel(%q, %d)): %s", test.channel, test.ttl, err)
+			continue
+		}
+		if channel != test.channel || ttl != test.ttl || !bytes.Equal(payload, test.payload) {
+			t.Errorf("splitChannel(addChannel(%q, %d, %q)) = %q, %d, %q", test.channel, test.ttl, test.payload, channel, ttl, payload)
+		}
+	}
+}
+
+func TestSplitChannelErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("GS"),
+		[]byte("GS\x01\x03"),
+		[]byte("XX\x01\x03\x00payload"),
+		[]byte("GS\x02\x03\x00payload"),
+		[]byte("GS\x01\x03\x05team"),
+	} {
+		if _, _, _, err := splitChannel(data); err == nil {
+			t.Errorf("splitChannel(%q) succeeded", data)
+		}
+	}
+}
+
+func TestChannelGroups(t *testing.T) {
+	ip4, ip6 := ChannelGroups("team", 9999)
+	if !ip4.IP.IsMulticast() || ip4.IP.To4() == nil || ip4.IP[12] != 239 || ip4.IP[13] != 255 {
+		t.Errorf("IPv4 group %s is not in 239.255.0.0/16", ip4)
+	}
+	if !ip6.IP.IsLinkLocalMulticast() {
+		t.Errorf("IPv6 group %s is not link-local", ip6)
+	}
+	if ip4.Port != 9999 || ip6.Port != 9999 {
+		t.Errorf("groups %s and %s are not on port 9999", ip4, ip6)
+	}
+
+	other4, other6 := ChannelGroups("other", 9999)
+	if other4.IP.Equal(ip4.IP) || other6.IP.Equal(ip6.IP) {
+		t.Errorf("channels share groups %s and %s", ip4, ip6)
+	}
+	again4, again6 := ChannelGroups("team", 9999)
+	if !again4.IP.Equal(ip4.IP) || !again6.IP.Equal(ip6.IP) {
+		t.Errorf("groups of a channel change")
+	}
+}
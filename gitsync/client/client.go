@@ -0,0 +1,149 @@
+// Package client is a Go client for gitsyncd. Client calls the daemon's
+// control API and Subscribe follows the changes it announces on its web
+// server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrNotRunning is returned when no daemon listens on the control socket
+var ErrNotRunning = errors.New("gitsyncd is not running")
+
+// Error is returned for calls the daemon refused
+type Error struct {
+	StatusCode int    // HTTP status of the reply
+	Message    string // the daemon's explanation, if it gave one
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("gitsyncd returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return e.Message
+}
+
+// Client calls the control API of a gitsyncd, see gitsync.ControlSocketPath
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// New returns a Client for the daemon listening on socket
+func New(socket string) *Client {
+	return &Client{
+		socket: socket,
+		http: &http.Client{
+			Timeout: time.Minute, // fetching every branch can take a while
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+				}}}}
+}
+
+// ForRepo returns a Client for the daemon watching the repo at dir, which must
+// be the top directory of the repo
+func ForRepo(dir string) *Client {
+	return New(gitsync.ControlSocketPath(dir))
+}
+
+// Socket returns the control socket c calls
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+// call makes a request to the daemon, sending body, if not nil, and decoding
+// the response into v
+func (c *Client) call(method, path string, body, v interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, "http://gitsyncd"+path, &buf)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return fmt.Errorf("%w (cannot reach %s)", ErrNotRunning, c.socket)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e gitsync.ControlError
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &Error{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Status describes the daemon
+func (c *Client) Status() (status gitsync.Status, err error) {
+	err = c.call("GET", "/status", nil, &status)
+	return status, err
+}
+
+// Peers lists the peers the daemon heard from
+func (c *Client) Peers() (peers []gitsync.Peer, err error) {
+	err = c.call("GET", "/peers", nil, &peers)
+	return peers, err
+}
+
+// Branches lists the peers' branches mirrored in the repo
+func (c *Client) Branches() (mirrors []gitsync.Mirror, err error) {
+	err = c.call("GET", "/branches", nil, &mirrors)
+	return mirrors, err
+}
+
+// Events returns the last limit events, oldest first, or all those the daemon
+// keeps if limit is 0
+func (c *Client) Events(limit int) (events []gitsync.Event, err error) {
+	err = c.call("GET", fmt.Sprintf("/events?limit=%d", limit), nil, &events)
+	return events, err
+}
+
+// EventsSince returns the events kept that came after the one numbered seq,
+// oldest first, to follow the daemon's activity by polling
+func (c *Client) EventsSince(seq uint64) (events []gitsync.Event, err error) {
+	err = c.call("GET", fmt.Sprintf("/events?since=%d", seq), nil, &events)
+	return events, err
+}
+
+// Fetch fetches a mirror branch again, or all of them if branch is empty
+func (c *Client) Fetch(branch string) (results []gitsync.FetchResult, err error) {
+	err = c.call("POST", "/fetch", gitsync.FetchRequest{Branch: branch}, &results)
+	return results, err
+}
+
+// Cleanup deletes the mirror branches
+func (c *Client) Cleanup() error {
+	var reply struct{}
+	return c.call("POST", "/cleanup", nil, &reply)
+}
+
+// SetLogLevel changes the daemon's log level
+func (c *Client) SetLogLevel(level string) error {
+	var reply struct{}
+	return c.call("POST", "/loglevel", gitsync.LogLevelRequest{Level: level}, &reply)
+}
+
+// Reload makes the daemon read its config, secret and peers files again
+func (c *Client) Reload() error {
+	var reply struct{}
+	return c.call("POST", "/reload", nil, &reply)
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"github.com/raybejjani/gitsync/gitsync"
+	"golang.org/x/net/websocket"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Delays between attempts to reconnect to the event stream. The delay doubles
+// after each failed attempt, up to maxRetryDelay.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// Subscription receives the changes a gitsyncd announces on its web server's
+// event stream. It reconnects whenever the connection is lost, resuming after
+// the last change received, so that changes announced while it was
+// disconnected are only missed if the daemon's history moved on without them.
+// A daemon that restarted without keeping its history numbers its changes
+// from 1 again, and sends them all; the subscription follows the new numbering.
+type Subscription struct {
+	addr, url, origin string
+	query             url.Values
+	changes           chan gitsync.GitChange
+	cancel            context.CancelFunc
+	seq               uint64 // Seq of the last change received, used by run only
+
+	lock sync.Mutex
+	err  error // why the last connection attempt or connection failed
+}
+
+// Subscribe follows the event stream of the daemon whose web server listens
+// on addr, given as host:port. Only changes selected by filter are sent by the
+// daemon, starting with those it keeps in its history. The subscription ends
+// when ctx is done or Close is called.
+func Subscribe(ctx context.Context, addr string, filter gitsync.ChangeFilter) *Subscription {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Subscription{
+		addr:    addr,
+		url:     "ws://" + addr + "/events",
+		origin:  "http://" + addr + "/",
+		query:   filter.Values(),
+		changes: make(chan gitsync.GitChange),
+		cancel:  cancel}
+
+	go s.run(ctx)
+	return s
+}
+
+// Changes returns the channel the changes are delivered on. It is closed when
+// the subscription ends.
+func (s *Subscription) Changes() <-chan gitsync.GitChange {
+	return s.changes
+}
+
+// Err returns why the subscription is disconnected, or nil if it is connected
+func (s *Subscription) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.cancel()
+}
+
+func (s *Subscription) setErr(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
+// run connects to the event stream until ctx is done, waiting longer after
+// each attempt that fails
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.changes)
+
+	delay := minRetryDelay
+	for {
+		connected, err := s.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.setErr(err)
+		if connected {
+			delay = minRetryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// receive delivers changes from one connection to the event stream, resuming
+// after the last one received, until it fails, reporting whether it connected
+// at all
+func (s *Subscription) receive(ctx context.Context) (connected bool, err error) {
+	if s.seq > 0 {
+		s.query.Set("since", strconv.FormatUint(s.seq, 10))
+	}
+	wsURL := s.url
+	if query := s.query.Encode(); query != "" {
+		wsURL += "?" + query
+	}
+	config, err := websocket.NewConfig(wsURL, s.origin)
+	if err != nil {
+		return false, err
+	}
+	conn, err := (&net.Dialer{Timeout: maxRetryDelay}).DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return false, err
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return false, err
+	}
+	s.setErr(nil)
+
+	// unblock the reads below when the subscription ends
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ws.Close()
+	}()
+
+	for {
+		var change gitsync.StreamedChange
+		if err = websocket.JSON.Receive(ws, &change); err != nil {
+			return true, err
+		}
+		select {
+		case s.changes <- change.GitChange:
+			// taken as is, rather than only when higher, so that the
+			// numbering of a restarted daemon, from 1 again, is followed
+			s.seq = change.Seq
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
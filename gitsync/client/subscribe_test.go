@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"golang.org/x/net/websocket"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEvents serves an event stream, resuming after ?since= as gitsyncd does.
+// The daemon has totals[i] changes when the i-th connection is made, a total
+// lower than the one before meaning the daemon restarted and numbers them
+// from 1 again. The i-th connection is dropped after the change numbered
+// dropAfter[i], if there is one for it. Changes are given IDs <restarts>.<seq>.
+type fakeEvents struct {
+	totals, dropAfter []uint64
+
+	lock    sync.Mutex
+	queries []string // the query of each connection, in order
+}
+
+func (f *fakeEvents) serve(ws *websocket.Conn) {
+	query := ws.Request().URL.Query()
+	f.lock.Lock()
+	f.queries = append(f.queries, query.Encode())
+	conn := len(f.queries) - 1
+	f.lock.Unlock()
+
+	var total, dropAfter uint64
+	restarts := 0
+	for i := 0; i <= conn && i < len(f.totals); i++ {
+		if i > 0 && f.totals[i] < f.totals[i-1] {
+			restarts++
+		}
+		total = f.totals[i]
+	}
+	if conn < len(f.dropAfter) {
+		dropAfter = f.dropAfter[conn]
+	}
+
+	var since uint64
+	if s := query.Get("since"); s != "" {
+		since, _ = strconv.ParseUint(s, 10, 64)
+	}
+	if since > total {
+		since = 0
+	}
+	for seq := since + 1; seq <= total; seq++ {
+		id := fmt.Sprintf("%d.%d", restarts, seq)
+		change := gitsync.StreamedChange{Seq: seq, GitChange: gitsync.GitChange{ID: id, User: "alice"}}
+		if err := websocket.JSON.Send(ws, change); err != nil {
+			return
+		}
+		if seq == dropAfter {
+			return
+		}
+	}
+	// wait for the client to go away
+	var buf [512]byte
+	for {
+		if _, err := ws.Read(buf[:]); err != nil {
+			return
+		}
+	}
+}
+
+// connections returns the query of each connection made so far
+func (f *fakeEvents) connections() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.queries...)
+}
+
+// startEvents serves f, returning its host:port
+func startEvents(t *testing.T, f *fakeEvents) string {
+	server := httptest.NewServer(websocket.Handler(f.serve))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// expectChanges checks that s delivers the changes with the given IDs, in
+// order, and nothing more
+func expectChanges(t *testing.T, s *Subscription, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		select {
+		case change := <-s.Changes():
+			if change.ID != id {
+				t.Fatalf("got change %s, want %s", change.ID, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no change %s", id)
+		}
+	}
+	select {
+	case change := <-s.Changes():
+		t.Errorf("got change %s after the last one", change.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// expectQueries checks the queries f was connected with
+func expectQueries(t *testing.T, f *fakeEvents, want ...string) {
+	t.Helper()
+	queries := f.connections()
+	if strings.Join(queries, " ") != strings.Join(want, " ") {
+		t.Errorf("connected with queries %v, want %v", queries, want)
+	}
+}
+
+func TestSubscribeResumes(t *testing.T) {
+	t.Parallel()
+	f := &fakeEvents{totals: []uint64{10}, dropAfter: []uint64{4}}
+	s := Subscribe(context.Background(), startEvents(t, f), gitsync.ChangeFilter{User: "alice"})
+	defer s.Close()
+
+	// every change is received once, in order, across the dropped connection
+	expectChanges(t, s, "0.1", "0.2", "0.3", "0.4", "0.5", "0.6", "0.7", "0.8", "0.9", "0.10")
+	expectQueries(t, f, "user=alice", "since=4&user=alice")
+	if err := s.Err(); err != nil {
+		t.Errorf("connected subscription has error %s", err)
+	}
+}
+
+func TestSubscribeRestart(t *testing.T) {
+	t.Parallel()
+	// the daemon restarts while disconnected, its changes numbered from 1
+	// again, and drops the connection once more
+	f := &fakeEvents{totals: []uint64{10, 3}, dropAfter: []uint64{4, 2}}
+	s := Subscribe(context.Background(), startEvents(t, f), gitsync.ChangeFilter{User: "alice"})
+	defer s.Close()
+
+	// the restarted daemon's changes are all received, and the subscription
+	// then resumes after those
+	expectChanges(t, s, "0.1", "0.2", "0.3", "0.4", "1.1", "1.2", "1.3")
+	expectQueries(t, f, "user=alice", "since=4&user=alice", "since=2&user=alice")
+}
+
+func TestSubscribeClose(t *testing.T) {
+	addr := startEvents(t, &fakeEvents{})
+
+	s := Subscribe(context.Background(), addr, gitsync.ChangeFilter{})
+	s.Close()
+	select {
+	case _, ok := <-s.Changes():
+		if ok {
+			t.Errorf("got a change from an empty stream")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("changes not closed once the subscription is closed")
+	}
+}
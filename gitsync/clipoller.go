@@ -3,14 +3,14 @@ package gitsync
 import (
 	log "github.com/ngmoco/timber"
 	"path"
-	"strings"
 	"time"
 )
 
 // PollDirectory will poll a git repo.
 // It will look for changes to branches and tags including creation and
-// deletion.
-func PollDirectory(l log.Logger, dirName string, repo Repo, changes chan GitChange, period time.Duration) {
+// deletion. Branches skip returns true for, such as mirror branches, are not
+// announced.
+func PollDirectory(l log.Logger, dirName string, repo Repo, changes chan GitChange, period time.Duration, skip func(branch string) bool) {
 	l.Info("Watching %s as %s\n", repo, dirName)
 	defer l.Info("Stopped watching %s as %s\n", repo, dirName)
 
@@ -39,7 +39,7 @@ func PollDirectory(l log.Logger, dirName string, repo Repo, changes chan GitChan
 			continue
 		}
 		for _, branch := range branches {
-			if strings.HasPrefix(branch.RefName, "gitsync-") {
+			if skip(branch.RefName) {
 				continue
 			}
 			var (
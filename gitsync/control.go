@@ -0,0 +1,136 @@
+package gitsync
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Types exchanged over gitsyncd's control API, served over HTTP on a Unix
+// socket, and its web server's /api. They are encoded as JSON.
+
+// maxSocketPath is the longest Unix socket path allowed everywhere we run,
+// macOS having the shortest limit
+const maxSocketPath = 103
+
+// ControlSocketPath returns where the control socket of the repo at dir is:
+// .git/gitsync/control.sock in the repo or, if that path is too long for a
+// socket, a file named after the repo in the user's runtime directory. Without
+// XDG_RUNTIME_DIR, that is a directory of our own in the temporary directory,
+// which gitsyncd creates only reachable by us.
+func ControlSocketPath(dir string) string {
+	path := filepath.Join(dir, ".git", "gitsync", "control.sock")
+	if len(path) <= maxSocketPath {
+		return path
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join(os.TempDir(), "gitsync-"+strconv.Itoa(os.Getuid()))
+	}
+	sum := sha1.Sum([]byte(dir))
+	return filepath.Join(runtimeDir, "gitsync-"+hex.EncodeToString(sum[:8])+".sock")
+}
+
+// Kinds of Event
+const (
+	EventChange     = "change"      // a peer announced a change
+	EventFetch      = "fetch"       // a peer's branch was fetched
+	EventFetchError = "fetch-error" // fetching a peer's branch failed
+	EventCleanup    = "cleanup"     // the mirror branches were deleted
+	EventLogLevel   = "loglevel"    // the log level was changed
+	EventReload     = "reload"      // the configuration was reloaded
+)
+
+// Event is something the daemon saw or did
+type Event struct {
+	Seq     uint64 // numbers the events from 1, in the order they happened
+	Time    time.Time
+	Kind    string // one of the Event* kinds
+	Message string
+	Change  *GitChange // the change the event is about, if any
+}
+
+// StreamedChange is a change as sent on the web server's /events websocket.
+// Seq is that of the EventChange event recording it, so that a client that
+// lost its connection can resume the stream with /events?since=<seq>.
+type StreamedChange struct {
+	Seq uint64
+	GitChange
+}
+
+// Peer is a peer the daemon has received announcements from
+type Peer struct {
+	PeerID      string
+	User        string
+	HostIp      string
+	LastSeen    time.Time
+	KeyMismatch bool   // its key is not the one approved for User
+	CheckedOut  string // branch it has checked out, as last announced, if known
+}
+
+// Mirror is a local branch holding a peer's branch
+type Mirror struct {
+	Branch     string     // local branch, see MirrorNaming
+	Current    string     // commit the local branch is at, empty if not fetched
+	Tip        *Commit    // the commit at Current, nil if not fetched
+	Change     *GitChange // last announcement for the branch, nil if none since the daemon started
+	FetchError string     // why the last fetch failed, if it did
+}
+
+// Status describes a running daemon
+type Status struct {
+	User       string
+	Path       string // repo the daemon watches
+	PeerID     string
+	RepoID     string // root commit of the repo, or the ID given with -repoid
+	Started    time.Time
+	Naming     MirrorNaming // how the mirror branches are named
+	Peers      int          // peers heard from
+	Transports []TransportStatus
+}
+
+// TransportStatus is the state of one of the daemon's transports
+type TransportStatus struct {
+	Name string
+	TransportState
+}
+
+// RepoInfo describes a repo a daemon watches
+type RepoInfo struct {
+	Name string // directory name, as peers know it
+	Path string
+	ID   string // see Status.RepoID
+}
+
+// Commit is a commit on a peer's branch
+type Commit struct {
+	Hash    string
+	Author  string
+	Time    time.Time // when it was authored
+	Subject string
+}
+
+// FetchRequest is the body of a fetch call
+type FetchRequest struct {
+	Branch string // mirror branch to fetch again, empty for all of them
+}
+
+// FetchResult is the outcome of fetching one branch
+type FetchResult struct {
+	Branch string
+	Error  string // empty if the fetch succeeded
+}
+
+// LogLevelRequest is the body of a loglevel call
+type LogLevelRequest struct {
+	Level string // one of the levels gitsyncd's -loglevel takes
+}
+
+// ControlError is the body of a failed call
+type ControlError struct {
+	Error string
+}
@@ -0,0 +1,228 @@
+package gitsync
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// The parts of DNS needed for DNS-SD over mDNS, see RFC 1035, 6762 and 6763
+
+const (
+	dnsTypeA    = 1
+	dnsTypePTR  = 12
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+	dnsTypeSRV  = 33
+	dnsTypeANY  = 255
+
+	dnsClassIN         = 1
+	dnsClassCacheFlush = 0x8000 // set on records only the sender answers for
+	dnsClassMask       = 0x7fff // class without the mDNS flag bit
+
+	dnsFlagResponse = 0x8400 // response, authoritative answer
+	dnsMaxPointers  = 16     // compression pointers followed in one name
+)
+
+var errShortDNS = errors.New("truncated DNS message")
+
+// dnsQuestion asks for the records of a type for a name
+type dnsQuestion struct {
+	Name string
+	Type uint16
+}
+
+// dnsRecord is a resource record. Which of the data fields is used depends on
+// Type.
+type dnsRecord struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+
+	Target string   // PTR and SRV
+	Port   uint16   // SRV
+	Text   []string // TXT
+	IP     net.IP   // A and AAAA
+}
+
+// dnsMessage is a query or a response. Records holds the answer, authority
+// and additional records of a received message, and is sent as answers.
+type dnsMessage struct {
+	Response  bool
+	Questions []dnsQuestion
+	Records   []dnsRecord
+}
+
+// appendName appends name, a dot separated list of labels, uncompressed
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// pack encodes the message
+func (m *dnsMessage) pack() []byte {
+	b := make([]byte, 12, 512)
+	if m.Response {
+		binary.BigEndian.PutUint16(b[2:], dnsFlagResponse)
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Records)))
+
+	for _, q := range m.Questions {
+		b = appendName(b, q.Name)
+		b = append(b, byte(q.Type>>8), byte(q.Type), 0, dnsClassIN)
+	}
+
+	for _, r := range m.Records {
+		b = appendName(b, r.Name)
+		b = append(b, byte(r.Type>>8), byte(r.Type), byte(r.Class>>8), byte(r.Class),
+			byte(r.TTL>>24), byte(r.TTL>>16), byte(r.TTL>>8), byte(r.TTL), 0, 0)
+		start := len(b)
+
+		switch r.Type {
+		case dnsTypePTR:
+			b = appendName(b, r.Target)
+		case dnsTypeSRV:
+			b = append(b, 0, 0, 0, 0, byte(r.Port>>8), byte(r.Port))
+			b = appendName(b, r.Target)
+		case dnsTypeTXT:
+			for _, s := range r.Text {
+				if len(s) > 255 {
+					s = s[:255]
+				}
+				b = append(b, byte(len(s)))
+				b = append(b, s...)
+			}
+		case dnsTypeA:
+			b = append(b, r.IP.To4()...)
+		case dnsTypeAAAA:
+			b = append(b, r.IP.To16()...)
+		}
+		binary.BigEndian.PutUint16(b[start-2:], uint16(len(b)-start))
+	}
+	return b
+}
+
+// readName reads the possibly compressed name at off in msg, returning it and
+// the offset just past it
+func readName(msg []byte, off int) (name string, next int, err error) {
+	var (
+		labels   []string
+		pointers = 0
+	)
+	next = -1
+	for {
+		if off >= len(msg) {
+			return "", 0, errShortDNS
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errShortDNS
+			}
+			if pointers++; pointers > dnsMaxPointers {
+				return "", 0, errors.New("too many compression pointers")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errShortDNS
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// unpackDNS decodes msg. Records of types we do not use are skipped.
+func unpackDNS(msg []byte) (m *dnsMessage, err error) {
+	if len(msg) < 12 {
+		return nil, errShortDNS
+	}
+	m = &dnsMessage{Response: msg[2]&0x80 != 0}
+	var (
+		questions = int(binary.BigEndian.Uint16(msg[4:]))
+		records   = int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+		off       = 12
+	)
+
+	for i := 0; i < questions; i++ {
+		var q dnsQuestion
+		if q.Name, off, err = readName(msg, off); err != nil {
+			return nil, err
+		}
+		if off+4 > len(msg) {
+			return nil, errShortDNS
+		}
+		q.Type = binary.BigEndian.Uint16(msg[off:])
+		off += 4
+		m.Questions = append(m.Questions, q)
+	}
+
+	for i := 0; i < records; i++ {
+		var r dnsRecord
+		if r.Name, off, err = readName(msg, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(msg) {
+			return nil, errShortDNS
+		}
+		r.Type = binary.BigEndian.Uint16(msg[off:])
+		r.Class = binary.BigEndian.Uint16(msg[off+2:])
+		r.TTL = binary.BigEndian.Uint32(msg[off+4:])
+		size := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+size > len(msg) {
+			return nil, errShortDNS
+		}
+		data := msg[off : off+size]
+
+		switch r.Type {
+		case dnsTypePTR:
+			r.Target, _, err = readName(msg, off)
+		case dnsTypeSRV:
+			if size < 7 {
+				return nil, errShortDNS
+			}
+			r.Port = binary.BigEndian.Uint16(data[4:])
+			r.Target, _, err = readName(msg, off+6)
+		case dnsTypeTXT:
+			for len(data) > 0 {
+				n := int(data[0])
+				if 1+n > len(data) {
+					return nil, errShortDNS
+				}
+				r.Text = append(r.Text, string(data[1:1+n]))
+				data = data[1+n:]
+			}
+		case dnsTypeA, dnsTypeAAAA:
+			r.IP = net.IP(append([]byte(nil), data...))
+		default:
+			off += size
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		off += size
+		m.Records = append(m.Records, r)
+	}
+	return m, nil
+}
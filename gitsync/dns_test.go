@@ -0,0 +1,76 @@
+package gitsync
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDNSPackUnpack(t *testing.T) {
+	msg := &dnsMessage{
+		Response:  true,
+		Questions: []dnsQuestion{{Name: ServiceType, Type: dnsTypePTR}},
+		Records: []dnsRecord{
+			{Name: ServiceType, Type: dnsTypePTR, Class: dnsClassIN, TTL: 4500,
+				Target: "alice on repo." + ServiceType},
+			{Name: "alice on repo." + ServiceType, Type: dnsTypeSRV, Class: dnsClassIN | dnsClassCacheFlush, TTL: 120,
+				Port: 9418, Target: "host.local."},
+			{Name: "alice on repo." + ServiceType, Type: dnsTypeTXT, Class: dnsClassIN, TTL: 4500,
+				Text: []string{"user=alice", "repo=abc123", ""}},
+			{Name: "host.local.", Type: dnsTypeA, Class: dnsClassIN, TTL: 120,
+				IP: net.IPv4(192, 0, 2, 1).To4()},
+			{Name: "host.local.", Type: dnsTypeAAAA, Class: dnsClassIN, TTL: 120,
+				IP: net.ParseIP("2001:db8::1")},
+		}}
+
+	got, err := unpackDNS(msg.pack())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("unpackDNS(pack()) = %+v, want %+v", got, msg)
+	}
+}
+
+func TestDNSUnpackCompressed(t *testing.T) {
+	// a response naming host.local. twice, the second time through a pointer
+	// to the first
+	msg := []byte{
+		0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 1,
+		4, 'h', 'o', 's', 't', 5, 'l', 'o', 'c', 'a', 'l', 0, // at 12
+		0, dnsTypeA, 0, dnsClassIN, 0, 0, 0, 120, 0, 4, 192, 0, 2, 1,
+		0xc0, 12,
+		0, 99, 0, dnsClassIN, 0, 0, 0, 120, 0, 1, 0, // a type we skip
+	}
+	m, err := unpackDNS(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Records) != 1 || m.Records[0].Name != "host.local." || !m.Records[0].IP.Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Errorf("unpackDNS = %+v", m.Records)
+	}
+}
+
+func TestDNSUnpackErrors(t *testing.T) {
+	valid := (&dnsMessage{Records: []dnsRecord{
+		{Name: "host.local.", Type: dnsTypeSRV, Class: dnsClassIN, Port: 1, Target: "host.local."},
+	}}).pack()
+
+	for _, test := range []struct {
+		name string
+		msg  []byte
+	}{
+		{"empty", nil},
+		{"short header", valid[:11]},
+		{"truncated record", valid[:len(valid)-3]},
+		{"missing question", []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}},
+		{"pointer loop", []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xc0, 12, 0, 1, 0, 1}},
+		{"label past the end", []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 9, 'a'}},
+		{"short SRV", []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, dnsTypeSRV, 0, 1, 0, 0, 0, 0, 0, 2, 0, 0}},
+		{"TXT string past the end", []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, dnsTypeTXT, 0, 1, 0, 0, 0, 0, 0, 2, 5, 'a'}},
+	} {
+		if m, err := unpackDNS(test.msg); err == nil {
+			t.Errorf("%s: unpackDNS = %+v", test.name, m)
+		}
+	}
+}
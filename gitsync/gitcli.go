@@ -39,6 +39,34 @@ func NewCliRepo(userName string, repoAbsPath string) (repo *cliReader, err error
 		userName: userName}, nil
 }
 
+// idRepo is a Repo told apart by an ID its team chose, rather than by its root
+// commit
+type idRepo struct {
+	Repo
+	id string
+}
+
+// WithRepoID returns repo, identified by id rather than by its root commit.
+// This suits repos with several root commits, or whose history was rewritten,
+// as long as all peers use the same id.
+func WithRepoID(repo Repo, id string) Repo {
+	return idRepo{Repo: repo, id: id}
+}
+
+func (repo idRepo) RootCommit() (string, error) {
+	return repo.id, nil
+}
+
+func (repo idRepo) Branches() (branches []*GitChange, err error) {
+	if branches, err = repo.Repo.Branches(); err != nil {
+		return nil, err
+	}
+	for _, branch := range branches {
+		branch.RootCommit = repo.id
+	}
+	return branches, nil
+}
+
 func (repo *cliReader) String() string {
 	return repo.repoPath
 }
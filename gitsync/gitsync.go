@@ -7,13 +7,17 @@ import (
 )
 
 type GitChange struct {
-	User          string // username at host
+	ID            string // unique per announcement, the same in every copy sent
+	PeerID        string // installation the change comes from, see LoadPeerID
+	User          string // username at host, for display only
 	HostIp        string // IP address of host
+	FetchPort     int    // port of the host's encrypted fetch service, 0 if it serves plain git://
 	RepoName      string // name of repo directory
 	RefName       string // name of reference
 	Prev, Current string // previous and current reference for branch
 	RootCommit    string // ref hash of the first commit (assuming there is only one)
 	CheckedOut    bool
+	KeyMismatch   bool // set on receipt if the sender's key is not the one pinned for User
 }
 
 func (change GitChange) FromRepo(repo Repo) bool {
@@ -23,3 +27,10 @@ func (change GitChange) FromRepo(repo Repo) bool {
 	}
 	return change.RootCommit == repoRoot
 }
+
+// MirrorBranch is the name of the local branch the change's branch is fetched
+// into with the default MirrorNaming. It holds the peer ID, so that users of
+// the same name do not clash.
+func (change GitChange) MirrorBranch() string {
+	return MirrorNaming{}.Branch(change)
+}
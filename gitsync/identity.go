@@ -0,0 +1,121 @@
+package gitsync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// signedMessage is a payload signed by the sender's identity
+type signedMessage struct {
+	PublicKey []byte // sender's ed25519 public key
+	Payload   []byte // gob encoded GitChange
+	Signature []byte // signature of Payload
+}
+
+// Identity is the ed25519 key pair a daemon signs its announcements with. It
+// is generated once and kept on disk so peers can pin it.
+type Identity struct {
+	key ed25519.PrivateKey
+}
+
+// LoadIdentity reads the identity stored at path, generating and saving a new
+// one if the file does not exist.
+func LoadIdentity(path string) (*Identity, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newIdentity(path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return &Identity{key: edKey}, nil
+}
+
+// newIdentity generates an identity and saves it to path
+func newIdentity(path string) (*Identity, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return &Identity{key: key}, nil
+}
+
+// PublicKey returns the public half of the identity
+func (id *Identity) PublicKey() ed25519.PublicKey {
+	return id.key.Public().(ed25519.PublicKey)
+}
+
+// String returns the public key in the form used in the known peers file
+func (id *Identity) String() string {
+	return FormatKey(id.PublicKey())
+}
+
+// Sign wraps payload in a message signed with the identity
+func (id *Identity) Sign(payload []byte) ([]byte, error) {
+	msg := signedMessage{
+		PublicKey: id.PublicKey(),
+		Payload:   payload,
+		Signature: ed25519.Sign(id.key, payload),
+	}
+
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OpenSigned verifies a message produced by Identity.Sign, returning its
+// payload and the key that signed it.
+func OpenSigned(data []byte) (payload []byte, key ed25519.PublicKey, err error) {
+	var msg signedMessage
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&msg); err != nil {
+		return nil, nil, fmt.Errorf("malformed message: %s", err)
+	}
+	if len(msg.PublicKey) != ed25519.PublicKeySize {
+		return nil, nil, errors.New("bad public key")
+	}
+	if !ed25519.Verify(msg.PublicKey, msg.Payload, msg.Signature) {
+		return nil, nil, errors.New("bad signature")
+	}
+	return msg.Payload, msg.PublicKey, nil
+}
+
+// FormatKey returns the printable form of a public key
+func FormatKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
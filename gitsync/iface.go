@@ -0,0 +1,147 @@
+package gitsync
+
+import (
+	"fmt"
+	"net"
+)
+
+// MulticastOptions control how multicast groups are joined and how datagrams
+// are sent to them
+type MulticastOptions struct {
+	Interface string // interface name, or a CIDR one of its addresses is in. Empty for the system's choice
+	TTL       int    // IPv4 TTL and IPv6 hop limit of sent datagrams, 0 for the system default
+	Loopback  bool   // deliver our own datagrams to listeners on this host
+}
+
+// ResolveInterface finds the interface described by spec, which is either an
+// interface name or a CIDR that one of the interface's addresses is in. For a
+// CIDR, the subnet is also returned.
+func ResolveInterface(spec string) (iface *net.Interface, subnet *net.IPNet, err error) {
+	if _, subnet, err = net.ParseCIDR(spec); err != nil {
+		iface, err = net.InterfaceByName(spec)
+		return iface, nil, err
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && subnet.Contains(ipNet.IP) {
+				return &ifaces[i], subnet, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("no interface has an address in %s", subnet)
+}
+
+// multicastInterface picks the first interface that is up, multicast capable
+// and has an address of the wanted family. IPv6 link-local groups cannot be
+// used without one.
+func multicastInterface(ipv6 bool) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if _, err := interfaceAddr(iface, ipv6, nil); err == nil {
+			return iface, nil
+		}
+	}
+	return nil, fmt.Errorf("no multicast interface with an IPv6(%t) address", ipv6)
+}
+
+// interfaceAddr returns an address of iface in the wanted family. One inside
+// subnet is preferred, if subnet is not nil, then a global address over a
+// link-local one.
+func interfaceAddr(iface *net.Interface, ipv6 bool, subnet *net.IPNet) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var best net.IP
+	bestScore := -1
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || (ipNet.IP.To4() == nil) != ipv6 {
+			continue
+		}
+
+		score := 0
+		if subnet != nil && subnet.Contains(ipNet.IP) {
+			score += 2
+		}
+		if !ipNet.IP.IsLinkLocalUnicast() {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = ipNet.IP, score
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("%s has no IPv6(%t) address", iface.Name, ipv6)
+	}
+	return best, nil
+}
+
+// isBroadcastAddr reports whether ip is the limited broadcast address or the
+// broadcast address of one of our IPv4 subnets
+func isBroadcastAddr(ip net.IP) bool {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return false
+	}
+	if ip4.Equal(net.IPv4bcast) {
+		return true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || len(ipNet.Mask) != net.IPv4len {
+			continue
+		}
+		bcast := make(net.IP, net.IPv4len)
+		for i := range bcast {
+			bcast[i] = ipNet.IP.To4()[i] | ^ipNet.Mask[i]
+		}
+		if ones, bits := ipNet.Mask.Size(); ones < bits-1 && ip4.Equal(bcast) {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceOf returns the name of the interface holding ip, or "unknown"
+func interfaceOf(ip net.IP) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "unknown"
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Name
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,396 @@
+package gitsync
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Trust is how a peer's key relates to the keys pinned for it
+type Trust int
+
+const (
+	TrustPinned   Trust = iota // first contact with the peer, the key is now pinned
+	TrustKnown                 // the key is trusted for the peer
+	TrustMismatch              // the peer has a different key pinned
+	TrustRevoked               // the key has been revoked
+)
+
+func (t Trust) String() string {
+	switch t {
+	case TrustPinned:
+		return "pinned"
+	case TrustKnown:
+		return "known"
+	case TrustMismatch:
+		return "mismatch"
+	case TrustRevoked:
+		return "revoked"
+	}
+	return fmt.Sprintf("Trust(%d)", int(t))
+}
+
+// States of a key in the known peers file
+const (
+	KeyTrusted = "trusted" // the key was approved, announcements signed with it are accepted
+	KeyPinned  = "pinned"  // the key was the first seen for the peer, announcements signed with it are accepted
+	KeyPending = "pending" // the key differs from a trusted one and awaits approval
+	KeyRevoked = "revoked" // announcements signed with the key are refused
+)
+
+// maxUnapproved is how many pinned and pending keys may be recorded. Anyone
+// can send keys, so beyond that new ones are only reported as a mismatch.
+const maxUnapproved = 256
+
+// PeerKey is an entry in the known peers file
+type PeerKey struct {
+	PeerID string // peer the key is pinned for, empty if pinned for User only
+	User   string // the peer's user name, as a label
+	Key    string // as returned by FormatKey
+	State  string // one of KeyTrusted, KeyPinned, KeyPending or KeyRevoked
+}
+
+// pinnedFor reports whether k is pinned for the peer. Peers too old to send a
+// peer ID are told apart by user name.
+func (k PeerKey) pinnedFor(peerID, user string) bool {
+	if peerID == "" {
+		return k.PeerID == "" && k.User == user
+	}
+	return k.PeerID == peerID
+}
+
+// KnownPeers pins the keys seen for each peer, trust-on-first-use style. Peers
+// are told apart by peer ID, as several may share a user name. It is backed by
+// a file with one "<peer id> <user> <key> <state>" entry per line, which is
+// re-read whenever it changes so it can be edited while the daemon runs. The
+// peer ID is - for keys pinned for any peer of the user.
+//
+// Files from before keys were pinned by peer ID hold "<user> <key> <state>"
+// entries. These are read as pinned for the user only, and the first of the
+// user's peers presenting the key takes the entry over.
+type KnownPeers struct {
+	path string
+
+	sync.Mutex           // lock keys and modTime
+	keys       []PeerKey // entries in file order
+	modTime    time.Time // modification time of the file when last read
+}
+
+// checkField checks that value, named what, can be written to the file, whose
+// fields are separated by whitespace and whose lines starting with # are
+// comments. User names and peer IDs come from the network, so must not be able
+// to add entries.
+func checkField(what, value string) error {
+	if value == "" || value == "-" {
+		return fmt.Errorf("empty %s", what)
+	}
+	if value[0] == '#' {
+		return fmt.Errorf("%s %q starts with #", what, value)
+	}
+	for _, r := range value {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("%s %q holds whitespace or control characters", what, value)
+		}
+	}
+	return nil
+}
+
+// checkPeer checks the peer's user name and, if it has one, peer ID
+func checkPeer(peerID, user string) error {
+	if err := checkField("user name", user); err != nil {
+		return err
+	}
+	if peerID != "" {
+		return checkField("peer ID", peerID)
+	}
+	return nil
+}
+
+// LoadKnownPeers reads the known peers file at path. A missing file is treated
+// as empty and is created when the first key is pinned.
+func LoadKnownPeers(path string) (*KnownPeers, error) {
+	kp := &KnownPeers{path: path}
+	if err := kp.reload(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+// reload re-reads the file if it changed since it was last read. It must be
+// called with the lock held.
+func (kp *KnownPeers) reload() error {
+	fi, err := os.Stat(kp.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fi.ModTime().Equal(kp.modTime) {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(kp.path)
+	if err != nil {
+		return err
+	}
+
+	var keys []PeerKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 3: // from before keys were pinned by peer ID
+			fields = append([]string{"-"}, fields...)
+		case 4:
+		default:
+			return fmt.Errorf("%s:%d: expected <peer id> <user> <key> <state>", kp.path, line)
+		}
+		switch fields[3] {
+		case KeyTrusted, KeyPinned, KeyPending, KeyRevoked:
+		default:
+			return fmt.Errorf("%s:%d: unknown key state %s", kp.path, line, fields[3])
+		}
+		k := PeerKey{PeerID: fields[0], User: fields[1], Key: fields[2], State: fields[3]}
+		if k.PeerID == "-" {
+			k.PeerID = ""
+		}
+		keys = append(keys, k)
+	}
+
+	kp.keys = keys
+	kp.modTime = fi.ModTime()
+	return nil
+}
+
+// save writes the entries out. It must be called with the lock held.
+func (kp *KnownPeers) save() error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# gitsync known peers: <peer id, or - for any of the user's> <user> <key> <%s|%s|%s|%s>\n", KeyTrusted, KeyPinned, KeyPending, KeyRevoked)
+	for _, k := range kp.keys {
+		peerID := k.PeerID
+		if peerID == "" {
+			peerID = "-"
+		}
+		fmt.Fprintf(buf, "%s %s %s %s\n", peerID, k.User, k.Key, k.State)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(kp.path), 0700); err != nil {
+		return err
+	}
+	tmp := kp.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, kp.path); err != nil {
+		return err
+	}
+
+	if fi, err := os.Stat(kp.path); err == nil {
+		kp.modTime = fi.ModTime()
+	}
+	return nil
+}
+
+// Check returns how far key is trusted for the peer with the given ID and user
+// name. The first key seen for a peer is pinned, unless other keys are already
+// trusted for its user name and key is not one of them. A different key seen
+// later, or such a new peer's key, is recorded as pending, so it can be
+// approved with Approve. Peers that cannot be pinned, their user name or peer
+// ID not fitting in the file, are a mismatch.
+func (kp *KnownPeers) Check(peerID, user string, key ed25519.PublicKey) (Trust, error) {
+	if err := checkPeer(peerID, user); err != nil {
+		return TrustMismatch, err
+	}
+
+	kp.Lock()
+	defer kp.Unlock()
+
+	if err := kp.reload(); err != nil {
+		return TrustMismatch, err
+	}
+
+	var (
+		formatted = FormatKey(key)
+		peerKnown = false
+	)
+	for _, k := range kp.keys {
+		if !k.pinnedFor(peerID, user) {
+			continue
+		}
+		peerKnown = true
+		if k.Key == formatted {
+			return k.trust(), nil
+		}
+	}
+
+	// the peer takes over the key if it is pinned for its user only
+	if peerID != "" {
+		for i, k := range kp.keys {
+			if k.PeerID == "" && k.User == user && k.Key == formatted {
+				kp.keys[i].PeerID = peerID
+				return k.trust(), kp.save()
+			}
+		}
+	}
+
+	// the sender picks its peer ID, so a new peer of a user whose keys are
+	// already trusted may be someone else using the name: only the user's own
+	// keys are pinned for it
+	if !peerKnown {
+		userKey := false
+		for _, k := range kp.keys {
+			if k.User == user && k.trust() == TrustKnown {
+				peerKnown = true
+				userKey = userKey || k.Key == formatted
+			}
+		}
+		peerKnown = peerKnown && !userKey
+	}
+
+	unapproved := 0
+	for _, k := range kp.keys {
+		if k.State == KeyPinned || k.State == KeyPending {
+			unapproved++
+		}
+	}
+	if unapproved >= maxUnapproved {
+		return TrustMismatch, fmt.Errorf("%d keys await approval, not recording more", unapproved)
+	}
+
+	if peerKnown {
+		kp.keys = append(kp.keys, PeerKey{PeerID: peerID, User: user, Key: formatted, State: KeyPending})
+		return TrustMismatch, kp.save()
+	}
+
+	kp.keys = append(kp.keys, PeerKey{PeerID: peerID, User: user, Key: formatted, State: KeyPinned})
+	return TrustPinned, kp.save()
+}
+
+// trust returns how far an announcement signed with the entry's key is trusted
+func (k PeerKey) trust() Trust {
+	switch k.State {
+	case KeyTrusted, KeyPinned:
+		return TrustKnown
+	case KeyRevoked:
+		return TrustRevoked
+	}
+	return TrustMismatch
+}
+
+// Keys returns all entries
+func (kp *KnownPeers) Keys() ([]PeerKey, error) {
+	kp.Lock()
+	defer kp.Unlock()
+
+	if err := kp.reload(); err != nil {
+		return nil, err
+	}
+	return append([]PeerKey(nil), kp.keys...), nil
+}
+
+// Approved reports whether key was approved for some peer, rather than just
+// pinned on first use, which anyone sending a new key gets. Unlike Check, it
+// pins nothing.
+func (kp *KnownPeers) Approved(key ed25519.PublicKey) bool {
+	kp.Lock()
+	defer kp.Unlock()
+
+	if err := kp.reload(); err != nil {
+		return false
+	}
+	formatted := FormatKey(key)
+	for _, k := range kp.keys {
+		if k.Key == formatted && k.State == KeyTrusted {
+			return true
+		}
+	}
+	return false
+}
+
+// ofPeer reports whether k is pinned for peer, given as a user name or as a
+// peer ID or its first 8 characters at least
+func (k PeerKey) ofPeer(peer string) bool {
+	return k.User == peer || k.PeerID == peer || (len(peer) >= 8 && strings.HasPrefix(k.PeerID, peer))
+}
+
+// setState moves peer's keys starting with keyPrefix into state. If keyPrefix
+// is empty, all of peer's keys currently in one of the states from, or in any
+// state if from is empty, are moved. A complete key that is not yet known is added,
+// pinned for peer taken as a user name. It returns the number of keys changed.
+func (kp *KnownPeers) setState(peer, keyPrefix string, from []string, state string) (n int, err error) {
+	if err = checkField("user name or peer ID", peer); err != nil {
+		return 0, err
+	}
+
+	kp.Lock()
+	defer kp.Unlock()
+
+	if err = kp.reload(); err != nil {
+		return 0, err
+	}
+
+	matched := false
+	for i, k := range kp.keys {
+		if !k.ofPeer(peer) || !strings.HasPrefix(k.Key, keyPrefix) {
+			continue
+		}
+		if keyPrefix == "" && len(from) > 0 && !inStates(k.State, from) {
+			continue
+		}
+		matched = true
+		if k.State != state {
+			kp.keys[i].State = state
+			n++
+		}
+	}
+
+	if !matched && keyPrefix != "" {
+		if raw, err := base64.StdEncoding.DecodeString(keyPrefix); err != nil || len(raw) != ed25519.PublicKeySize {
+			return 0, fmt.Errorf("no key for %s matches %s", peer, keyPrefix)
+		}
+		kp.keys = append(kp.keys, PeerKey{User: peer, Key: keyPrefix, State: state})
+		n++
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+	return n, kp.save()
+}
+
+// inStates reports whether state is one of states
+func inStates(state string, states []string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Approve trusts the keys starting with keyPrefix of peer, a user name or a
+// peer ID, or all of its pinned and pending keys if keyPrefix is empty. It
+// returns the number of keys changed.
+func (kp *KnownPeers) Approve(peer, keyPrefix string) (n int, err error) {
+	return kp.setState(peer, keyPrefix, []string{KeyPinned, KeyPending}, KeyTrusted)
+}
+
+// Revoke refuses the keys starting with keyPrefix of peer, a user name or a
+// peer ID, or all of its keys if keyPrefix is empty. It returns the number of
+// keys changed.
+func (kp *KnownPeers) Revoke(peer, keyPrefix string) (n int, err error) {
+	return kp.setState(peer, keyPrefix, nil, KeyRevoked)
+}
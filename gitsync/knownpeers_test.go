@@ -0,0 +1,327 @@
+package gitsync
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) ed25519.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func newKnownPeers(t *testing.T) *KnownPeers {
+	kp, err := LoadKnownPeers(filepath.Join(t.TempDir(), "known_peers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kp
+}
+
+func checkTrust(t *testing.T, kp *KnownPeers, peerID, user string, key ed25519.PublicKey, want Trust) {
+	t.Helper()
+	got, err := kp.Check(peerID, user, key)
+	if err != nil {
+		t.Fatalf("Check(%s, %s): %s", peerID, user, err)
+	}
+	if got != want {
+		t.Errorf("Check(%s, %s) = %s, want %s", peerID, user, got, want)
+	}
+}
+
+func checkKeys(t *testing.T, kp *KnownPeers, want []PeerKey) {
+	t.Helper()
+	keys, err := kp.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("Keys() = %+v, want %+v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("Keys()[%d] = %+v, want %+v", i, keys[i], want[i])
+		}
+	}
+}
+
+func TestKnownPeersCheck(t *testing.T) {
+	var (
+		kp         = newKnownPeers(t)
+		alice, bob = newKey(t), newKey(t)
+		other      = newKey(t)
+	)
+	checkTrust(t, kp, "a1", "alice", alice, TrustPinned)
+	checkTrust(t, kp, "a1", "alice", alice, TrustKnown)
+	checkTrust(t, kp, "b1", "bob", bob, TrustPinned)
+	checkTrust(t, kp, "a1", "alice", other, TrustMismatch)
+	checkTrust(t, kp, "a1", "alice", other, TrustMismatch)
+
+	checkKeys(t, kp, []PeerKey{
+		{"a1", "alice", FormatKey(alice), KeyPinned},
+		{"b1", "bob", FormatKey(bob), KeyPinned},
+		{"a1", "alice", FormatKey(other), KeyPending},
+	})
+}
+
+func TestKnownPeersSharedUser(t *testing.T) {
+	var (
+		kp          = newKnownPeers(t)
+		one, two    = newKey(t), newKey(t)
+		old, oldest = newKey(t), newKey(t)
+	)
+	// a second install named dev has its key approved, being told apart from
+	// the first by peer ID
+	checkTrust(t, kp, "peer1", "dev", one, TrustPinned)
+	checkTrust(t, kp, "peer2", "dev", two, TrustMismatch)
+	if n, err := kp.Approve("peer2", ""); err != nil || n != 1 {
+		t.Fatalf("Approve(peer2) = %d, %v, want 1 key", n, err)
+	}
+	checkTrust(t, kp, "peer1", "dev", one, TrustKnown)
+	checkTrust(t, kp, "peer2", "dev", two, TrustKnown)
+
+	// but one cannot use the other's key
+	checkTrust(t, kp, "peer1", "dev", two, TrustMismatch)
+
+	// peers without an ID are told apart by user name
+	checkTrust(t, kp, "", "dev", old, TrustMismatch)
+	if n, err := kp.Approve("dev", FormatKey(old)); err != nil || n != 1 {
+		t.Fatalf("Approve(dev, old) = %d, %v, want 1 key", n, err)
+	}
+	checkTrust(t, kp, "", "dev", old, TrustKnown)
+	checkTrust(t, kp, "", "dev", oldest, TrustMismatch)
+	checkTrust(t, kp, "", "dev", one, TrustMismatch)
+
+	// revoking by peer ID, or a prefix of it, leaves the other peers alone
+	if n, err := kp.Revoke("peer2", ""); err != nil || n != 1 {
+		t.Fatalf("Revoke(peer2) = %d, %v, want 1 key", n, err)
+	}
+	checkTrust(t, kp, "peer2", "dev", two, TrustRevoked)
+	checkTrust(t, kp, "peer1", "dev", one, TrustKnown)
+}
+
+func TestKnownPeersMigrate(t *testing.T) {
+	var (
+		kp             = newKnownPeers(t)
+		alice, revoked = newKey(t), newKey(t)
+		other          = newKey(t)
+	)
+	old := "# gitsync known peers: <user> <key> <trusted|pending|revoked>\n" +
+		"alice " + FormatKey(alice) + " trusted\n" +
+		"alice " + FormatKey(revoked) + " revoked\n"
+	if err := os.WriteFile(kp.path, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp.modTime = kp.modTime.Add(-1)
+
+	// the first of alice's peers presenting a key takes it over
+	checkTrust(t, kp, "peer1", "alice", alice, TrustKnown)
+	checkTrust(t, kp, "peer2", "alice", revoked, TrustRevoked)
+	checkTrust(t, kp, "peer3", "alice", alice, TrustPinned)
+	checkTrust(t, kp, "peer4", "alice", other, TrustMismatch)
+
+	again, err := LoadKnownPeers(kp.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKeys(t, again, []PeerKey{
+		{"peer1", "alice", FormatKey(alice), KeyTrusted},
+		{"peer2", "alice", FormatKey(revoked), KeyRevoked},
+		{"peer3", "alice", FormatKey(alice), KeyPinned},
+		{"peer4", "alice", FormatKey(other), KeyPending},
+	})
+}
+
+func TestKnownPeersNewPeerOfUser(t *testing.T) {
+	var (
+		kp           = newKnownPeers(t)
+		alice, mally = newKey(t), newKey(t)
+	)
+	checkTrust(t, kp, "alice-id", "alice", alice, TrustPinned)
+
+	// anyone can send a new peer ID with alice's name, but not alice's key
+	checkTrust(t, kp, "mallory-id", "alice", mally, TrustMismatch)
+	checkTrust(t, kp, "mallory-id", "alice", mally, TrustMismatch)
+	checkTrust(t, kp, "other-id", "alice", mally, TrustMismatch)
+
+	// while alice's own key is trusted whichever peer ID it comes with
+	checkTrust(t, kp, "alice-id2", "alice", alice, TrustPinned)
+
+	checkKeys(t, kp, []PeerKey{
+		{"alice-id", "alice", FormatKey(alice), KeyPinned},
+		{"mallory-id", "alice", FormatKey(mally), KeyPending},
+		{"other-id", "alice", FormatKey(mally), KeyPending},
+		{"alice-id2", "alice", FormatKey(alice), KeyPinned},
+	})
+
+	// other users' peers are pinned as before
+	checkTrust(t, kp, "bob-id", "bob", newKey(t), TrustPinned)
+}
+
+func TestKnownPeersApproveRevoke(t *testing.T) {
+	var (
+		kp         = newKnownPeers(t)
+		old, newer = newKey(t), newKey(t)
+		third      = newKey(t)
+	)
+	checkTrust(t, kp, "alice-id", "alice", old, TrustPinned)
+	checkTrust(t, kp, "alice-id", "alice", newer, TrustMismatch)
+
+	// approving without a key approves the pinned and pending ones
+	if n, err := kp.Approve("alice", ""); err != nil || n != 2 {
+		t.Fatalf("Approve(alice) = %d, %v, want 2 keys", n, err)
+	}
+	checkTrust(t, kp, "alice-id", "alice", newer, TrustKnown)
+	checkTrust(t, kp, "alice-id", "alice", old, TrustKnown)
+
+	// by prefix
+	if n, err := kp.Revoke("alice", FormatKey(old)[:10]); err != nil || n != 1 {
+		t.Fatalf("Revoke(alice, old) = %d, %v, want 1 key", n, err)
+	}
+	checkTrust(t, kp, "alice-id", "alice", old, TrustRevoked)
+	checkTrust(t, kp, "alice-id", "alice", newer, TrustKnown)
+
+	// a complete key not seen yet is added
+	if n, err := kp.Approve("alice", FormatKey(third)); err != nil || n != 1 {
+		t.Fatalf("Approve(alice, third) = %d, %v, want 1 key", n, err)
+	}
+	checkTrust(t, kp, "alice-id", "alice", third, TrustKnown)
+	if _, err := kp.Approve("alice", "nosuchkey"); err == nil {
+		t.Errorf("Approve(alice, nosuchkey) succeeded")
+	}
+
+	// without a key all of the user's keys are revoked
+	if n, err := kp.Revoke("alice", ""); err != nil || n != 2 {
+		t.Fatalf("Revoke(alice) = %d, %v, want 2 keys", n, err)
+	}
+	checkTrust(t, kp, "alice-id", "alice", newer, TrustRevoked)
+	checkTrust(t, kp, "alice-id", "alice", third, TrustRevoked)
+}
+
+func TestKnownPeersFile(t *testing.T) {
+	var (
+		kp         = newKnownPeers(t)
+		alice, bob = newKey(t), newKey(t)
+	)
+	checkTrust(t, kp, "alice-id", "alice", alice, TrustPinned)
+	checkTrust(t, kp, "bob-id", "bob", bob, TrustPinned)
+	kp.Revoke("bob", "")
+
+	// a second reader sees the same entries
+	again, err := LoadKnownPeers(kp.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTrust(t, again, "alice-id", "alice", alice, TrustKnown)
+	checkTrust(t, again, "bob-id", "bob", bob, TrustRevoked)
+
+	// and edits made to the file while running
+	data, err := os.ReadFile(kp.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(data), FormatKey(bob)+" "+KeyRevoked, FormatKey(bob)+" "+KeyTrusted, 1)
+	if err = os.WriteFile(kp.path, []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp.modTime = kp.modTime.Add(-1) // the edit may not change the modification time
+	checkTrust(t, kp, "bob-id", "bob", bob, TrustKnown)
+
+	if err = os.WriteFile(kp.path, []byte("alice key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp.modTime = kp.modTime.Add(-1)
+	if _, err = kp.Check("alice-id", "alice", alice); err == nil {
+		t.Errorf("Check succeeded with a bad file")
+	}
+}
+
+func TestKnownPeersBadUsers(t *testing.T) {
+	var (
+		kp  = newKnownPeers(t)
+		key = newKey(t)
+	)
+	for _, user := range []string{
+		"",
+		"-",
+		"two words",
+		"eve\nvictim " + FormatKey(key) + " trusted",
+		"tab\there",
+		"#comment",
+		"bell\a",
+	} {
+		if trust, err := kp.Check("peer", user, key); err == nil || trust != TrustMismatch {
+			t.Errorf("Check(%q) = %s, %v, want a mismatch and an error", user, trust, err)
+		}
+		if _, err := kp.Approve(user, FormatKey(key)); err == nil {
+			t.Errorf("Approve(%q) succeeded", user)
+		}
+		if user == "" {
+			continue // peers without an ID are told apart by user name
+		}
+		if trust, err := kp.Check(user, "user", key); err == nil || trust != TrustMismatch {
+			t.Errorf("Check(peer ID %q) = %s, %v, want a mismatch and an error", user, trust, err)
+		}
+	}
+	if keys, _ := kp.Keys(); len(keys) != 0 {
+		t.Errorf("bad users pinned %+v", keys)
+	}
+
+	// the file stays readable
+	checkTrust(t, kp, "victim-id", "victim", key, TrustPinned)
+	if _, err := LoadKnownPeers(kp.path); err != nil {
+		t.Errorf("cannot read the file back: %s", err)
+	}
+}
+
+func TestKnownPeersApproved(t *testing.T) {
+	var (
+		kp         = newKnownPeers(t)
+		alice, bob = newKey(t), newKey(t)
+	)
+	checkTrust(t, kp, "alice-id", "alice", alice, TrustPinned)
+	checkTrust(t, kp, "bob-id", "bob", bob, TrustPinned)
+	if kp.Approved(alice) || kp.Approved(bob) {
+		t.Errorf("keys pinned on first use are approved")
+	}
+	if _, err := kp.Approve("alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !kp.Approved(alice) || kp.Approved(bob) {
+		t.Errorf("Approved(alice) = %v, Approved(bob) = %v after approving alice", kp.Approved(alice), kp.Approved(bob))
+	}
+}
+
+func TestKnownPeersUnapprovedCap(t *testing.T) {
+	var (
+		kp   = newKnownPeers(t)
+		keys = make([]ed25519.PublicKey, maxUnapproved)
+	)
+	for i := range keys {
+		keys[i] = newKey(t)
+		checkTrust(t, kp, fmt.Sprint("peer", i), fmt.Sprint("user", i), keys[i], TrustPinned)
+	}
+
+	// new keys are no longer recorded
+	if trust, err := kp.Check("peer-new", "new", newKey(t)); err == nil || trust != TrustMismatch {
+		t.Errorf("Check past the cap = %s, %v, want a mismatch and an error", trust, err)
+	}
+	if recorded, _ := kp.Keys(); len(recorded) != maxUnapproved {
+		t.Errorf("%d keys recorded, want %d", len(recorded), maxUnapproved)
+	}
+
+	// those recorded still are, and approving some makes room
+	checkTrust(t, kp, "peer0", "user0", keys[0], TrustKnown)
+	if _, err := kp.Approve("user0", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkTrust(t, kp, "peer-new", "new", newKey(t), TrustPinned)
+}
@@ -0,0 +1,437 @@
+package gitsync
+
+import (
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mdnsIP4Addr = &net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: 5353}
+	mdnsIP6Addr = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+)
+
+const (
+	ServiceType = "_gitsync._tcp.local." // DNS-SD service type daemons advertise
+
+	mdnsServicesName   = "_services._dns-sd._udp.local." // lists the service types on the network
+	mdnsHostTTL        = 120                             // seconds, for records naming us or our address
+	mdnsServiceTTL     = 4500                            // seconds, for the other records
+	mdnsBrowseInterval = 5 * time.Minute                 // how often to look for peers
+	mdnsMinInterval    = time.Second                     // least time between our multicast responses
+	gitPort            = 9418                            // port git daemon serves on
+)
+
+// Service is a gitsync daemon advertised with DNS-SD
+type Service struct {
+	Instance    string // DNS-SD instance name
+	User        string
+	Repo        string // root commit of the repo
+	Channel     string // channel the daemon is on
+	URL         string // where the repo can be fetched from
+	Addr        string // address the advertisement came from
+	UnicastPort int    // port the daemon listens on for unicast, 0 if it does not
+}
+
+// mdnsConn is our membership of one mDNS group
+type mdnsConn struct {
+	conn     *net.UDPConn
+	group    *net.UDPAddr
+	ip       net.IP    // our address in the group's family
+	lastSent time.Time // when we last multicast a response
+}
+
+// mdns advertises us as a DNS-SD service over mDNS and browses for other
+// daemons working on the same repo
+type mdns struct {
+	self      Service // what we advertise, URL and Addr are filled in per group
+	repoName  string
+	target    string // the name our SRV record points at, see targetName
+	fetchPort int    // port peers fetch from
+	opts      MulticastOptions
+	done      chan struct{} // closed on Close
+	broken    chan error    // read errors other than closing
+	heard     chan struct{} // holds a value once a read succeeds
+
+	sync.Mutex                    // lock conns and found
+	conns      []*mdnsConn        // one per address family
+	found      map[string]Service // instance and address -> service last seen
+}
+
+// instanceName builds a DNS-SD instance name, a single label of up to 63 bytes
+func instanceName(user, host, repoName string) string {
+	name := strings.Replace(fmt.Sprintf("%s@%s %s", user, host, repoName), ".", "-", -1)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// targetName builds the name our SRV record points at and whose addresses we
+// answer for. It is under gitsync.local. rather than our host name, which the
+// system's own responder answers for.
+func targetName(peerID, host string) string {
+	label := strings.Replace(peerID, ".", "-", -1)
+	if label == "" {
+		label = host
+	}
+	if len(label) > 63 {
+		label = label[:63]
+	}
+	return label + ".gitsync.local."
+}
+
+// newMDNS prepares to advertise self as the daemon with the given peer ID. The
+// mDNS groups are joined by run.
+func newMDNS(opts MulticastOptions, self Service, peerID, repoName string, fetchPort int) *mdns {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "gitsync"
+	}
+	host = strings.SplitN(host, ".", 2)[0]
+	self.Instance = instanceName(self.User, host, repoName)
+
+	return &mdns{
+		self:      self,
+		repoName:  repoName,
+		target:    targetName(peerID, host),
+		fetchPort: fetchPort,
+		opts:      opts,
+		done:      make(chan struct{}),
+		broken:    make(chan error, 2),
+		heard:     make(chan struct{}, 1),
+		found:     make(map[string]Service)}
+}
+
+// join joins the mDNS groups of both address families, skipping those that
+// cannot be joined, and announces us in them. Groups joined before are left.
+func (m *mdns) join(l log.Logger, found chan<- Service) {
+	m.leave(l, false)
+
+	var conns []*mdnsConn
+	for _, group := range []*net.UDPAddr{mdnsIP4Addr, mdnsIP6Addr} {
+		c, err := joinMDNS(group, m.opts)
+		if err != nil {
+			l.Error("Cannot join mDNS group %s: %s", group, err)
+			continue
+		}
+		l.Info("Advertising %q on mDNS group %s as %s", m.self.Instance, group, c.ip)
+		conns = append(conns, c)
+		go m.receive(l, c, found)
+	}
+
+	m.Lock()
+	m.conns = conns
+	m.Unlock()
+
+	// announce twice, a second apart, in case the first is lost
+	for i := 0; i < 2; i++ {
+		for _, c := range conns {
+			m.send(l, c, &dnsMessage{Response: true, Records: m.records(c, false)})
+		}
+		time.Sleep(mdnsMinInterval)
+	}
+}
+
+// leave leaves the groups joined, saying goodbye first if asked to
+func (m *mdns) leave(l log.Logger, goodbye bool) {
+	m.Lock()
+	conns := m.conns
+	m.conns = nil
+	m.Unlock()
+
+	for _, c := range conns {
+		if goodbye {
+			m.send(l, c, &dnsMessage{Response: true, Records: m.records(c, true)})
+		}
+		c.conn.Close()
+	}
+}
+
+// joinMDNS joins group. Unlike our own groups, mDNS messages must be sent from
+// the mDNS port with a TTL of 255, so the listening socket is used to send.
+func joinMDNS(group *net.UDPAddr, opts MulticastOptions) (c *mdnsConn, err error) {
+	var (
+		network = udpNetwork(group)
+		ipv6    = network == "udp6"
+		iface   *net.Interface
+		subnet  *net.IPNet
+	)
+	if opts.Interface != "" {
+		iface, subnet, err = ResolveInterface(opts.Interface)
+	} else {
+		iface, err = multicastInterface(ipv6)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c = &mdnsConn{group: group}
+	if c.ip, err = interfaceAddr(iface, ipv6, subnet); err != nil {
+		return nil, err
+	}
+	if ipv6 {
+		c.group = &net.UDPAddr{IP: group.IP, Port: group.Port, Zone: iface.Name}
+	}
+	if c.conn, err = net.ListenMulticastUDP(network, iface, c.group); err != nil {
+		return nil, err
+	}
+	if err = setMulticastOptions(c.conn, ipv6, iface.Index, c.ip, 255, opts.Loopback); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// records returns the records describing us to members of c's group
+func (m *mdns) records(c *mdnsConn, goodbye bool) []dnsRecord {
+	var (
+		instance = m.self.Instance + "." + ServiceType
+		target   = m.target
+		hostTTL  = uint32(mdnsHostTTL)
+		svcTTL   = uint32(mdnsServiceTTL)
+		addrType = uint16(dnsTypeA)
+		host     = c.ip.String()
+	)
+	if goodbye {
+		hostTTL, svcTTL = 0, 0
+	}
+	if c.ip.To4() == nil {
+		addrType = dnsTypeAAAA
+		host = "[" + host + "]"
+	}
+	if m.fetchPort != gitPort {
+		host = net.JoinHostPort(c.ip.String(), strconv.Itoa(m.fetchPort))
+	}
+
+	txt := []string{
+		"user=" + m.self.User,
+		"repo=" + m.self.Repo,
+		fmt.Sprintf("url=git://%s/%s", host, m.repoName)}
+	if m.self.Channel != "" {
+		txt = append(txt, "channel="+m.self.Channel)
+	}
+	if m.self.UnicastPort != 0 {
+		txt = append(txt, "port="+strconv.Itoa(m.self.UnicastPort))
+	}
+
+	return []dnsRecord{
+		{Name: ServiceType, Type: dnsTypePTR, Class: dnsClassIN, TTL: svcTTL, Target: instance},
+		{Name: instance, Type: dnsTypeSRV, Class: dnsClassIN | dnsClassCacheFlush, TTL: hostTTL, Port: uint16(m.fetchPort), Target: target},
+		{Name: instance, Type: dnsTypeTXT, Class: dnsClassIN | dnsClassCacheFlush, TTL: svcTTL, Text: txt},
+		{Name: target, Type: addrType, Class: dnsClassIN | dnsClassCacheFlush, TTL: hostTTL, IP: c.ip},
+	}
+}
+
+// send multicasts msg to c's group
+func (m *mdns) send(l log.Logger, c *mdnsConn, msg *dnsMessage) {
+	if _, err := c.conn.WriteToUDP(msg.pack(), c.group); err != nil {
+		l.Error("Cannot send to mDNS group %s: %s", c.group, err)
+	}
+}
+
+// run announces us, answers queries for our service and browses for peers,
+// passing those working on the same repo and channel on to found. The groups
+// are rejoined when the network changes, so the address we advertise stays
+// current, or reading fails, with backoff until a read succeeds or the groups
+// stay joined for minJoinedTime. It returns once m is closed.
+func (m *mdns) run(l log.Logger, found chan<- Service) {
+	var (
+		fingerprint = networkFingerprint()
+		retry       backoff
+		ticker      = time.NewTicker(netCheckInterval)
+		lastBrowse  time.Time
+		joined      = time.Now()
+	)
+	defer ticker.Stop()
+
+	m.join(l, found)
+	for {
+		m.Lock()
+		conns := m.conns
+		m.Unlock()
+
+		if len(conns) == 0 {
+			if !retry.wait(m.done) {
+				return
+			}
+			fingerprint = networkFingerprint()
+			m.join(l, found)
+			joined = time.Now()
+			continue
+		}
+
+		if time.Since(lastBrowse) >= mdnsBrowseInterval {
+			for _, c := range conns {
+				m.send(l, c, &dnsMessage{Questions: []dnsQuestion{{Name: ServiceType, Type: dnsTypePTR}}})
+			}
+			lastBrowse = time.Now()
+		}
+
+		select {
+		case <-m.done:
+			return
+		case err := <-m.broken:
+			l.Warn("Rejoining mDNS groups: %s", err)
+			select {
+			case <-m.heard:
+				retry.reset()
+			default:
+				if time.Since(joined) >= minJoinedTime {
+					retry.reset()
+				}
+			}
+			if !retry.wait(m.done) {
+				return
+			}
+		case <-ticker.C:
+			now := networkFingerprint()
+			if now == fingerprint {
+				continue
+			}
+			l.Info("Network changed, rejoining mDNS groups")
+			fingerprint = now
+		}
+		select {
+		case <-m.heard:
+		default:
+		}
+		m.join(l, found)
+		joined, lastBrowse = time.Now(), time.Time{}
+	}
+}
+
+// Close says goodbye, so peers forget us at once, and leaves the groups
+func (m *mdns) Close(l log.Logger) {
+	close(m.done)
+	m.leave(l, true)
+}
+
+// receive reads mDNS messages from c, answering queries and passing services
+// found in responses on to found
+func (m *mdns) receive(l log.Logger, c *mdnsConn, found chan<- Service) {
+	for {
+		b := make([]byte, 9000)
+		n, from, err := c.conn.ReadFromUDP(b)
+		if isClosed(err) {
+			return
+		} else if err != nil {
+			select {
+			case m.broken <- fmt.Errorf("cannot read from %s: %s", c.group, err):
+			default:
+			}
+			return
+		}
+		select {
+		case m.heard <- struct{}{}:
+		default:
+		}
+		msg, err := unpackDNS(b[:n])
+		if err != nil {
+			l.Fine("Dropping mDNS message from %s: %s", from, err)
+			continue
+		}
+
+		if !msg.Response {
+			m.answer(l, c, msg)
+			continue
+		}
+		for _, svc := range m.services(msg, from) {
+			select {
+			case found <- svc:
+			case <-m.done:
+				return
+			}
+		}
+	}
+}
+
+// answer responds to the questions in msg that are about us
+func (m *mdns) answer(l log.Logger, c *mdnsConn, msg *dnsMessage) {
+	var (
+		instance = m.self.Instance + "." + ServiceType
+		resp     = &dnsMessage{Response: true}
+	)
+	for _, q := range msg.Questions {
+		switch {
+		case strings.EqualFold(q.Name, mdnsServicesName) && (q.Type == dnsTypePTR || q.Type == dnsTypeANY):
+			resp.Records = append(resp.Records, dnsRecord{
+				Name: mdnsServicesName, Type: dnsTypePTR, Class: dnsClassIN, TTL: mdnsServiceTTL, Target: ServiceType})
+		case strings.EqualFold(q.Name, ServiceType) && (q.Type == dnsTypePTR || q.Type == dnsTypeANY),
+			strings.EqualFold(q.Name, instance):
+			resp.Records = append(resp.Records, m.records(c, false)...)
+		}
+	}
+	if len(resp.Records) == 0 {
+		return
+	}
+
+	m.Lock()
+	if time.Since(c.lastSent) < mdnsMinInterval {
+		m.Unlock()
+		return
+	}
+	c.lastSent = time.Now()
+	m.Unlock()
+	m.send(l, c, resp)
+}
+
+// services returns the daemons advertised in msg that work on our repo and
+// that are new or have changed since last seen
+func (m *mdns) services(msg *dnsMessage, from *net.UDPAddr) (services []Service) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, ptr := range msg.Records {
+		if ptr.Type != dnsTypePTR || !strings.EqualFold(ptr.Name, ServiceType) {
+			continue
+		}
+		svc := Service{
+			Instance: strings.TrimSuffix(ptr.Target, "."+ServiceType),
+			Addr:     (&net.IPAddr{IP: from.IP, Zone: from.Zone}).String()}
+		key := svc.Instance + " " + svc.Addr
+		if ptr.TTL == 0 {
+			delete(m.found, key)
+			continue
+		}
+		if svc.Instance == m.self.Instance {
+			continue
+		}
+
+		for _, txt := range msg.Records {
+			if txt.Type != dnsTypeTXT || !strings.EqualFold(txt.Name, ptr.Target) {
+				continue
+			}
+			for _, kv := range txt.Text {
+				parts := strings.SplitN(kv, "=", 2)
+				if len(parts) != 2 {
+					continue
+				}
+				switch parts[0] {
+				case "user":
+					svc.User = parts[1]
+				case "repo":
+					svc.Repo = parts[1]
+				case "channel":
+					svc.Channel = parts[1]
+				case "url":
+					svc.URL = parts[1]
+				case "port":
+					svc.UnicastPort, _ = strconv.Atoi(parts[1])
+				}
+			}
+		}
+
+		if svc.Repo != m.self.Repo || svc.Channel != m.self.Channel || m.found[key] == svc {
+			continue
+		}
+		m.found[key] = svc
+		services = append(services, svc)
+	}
+	return services
+}
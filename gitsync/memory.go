@@ -0,0 +1,90 @@
+package gitsync
+
+import (
+	log "github.com/ngmoco/timber"
+	"net"
+	"sync"
+)
+
+// memoryQueueSize is how many announcements a MemoryTransport holds before it
+// drops new ones
+const memoryQueueSize = 128
+
+// MemoryNetwork connects MemoryTransports within one process, so several
+// peers can be run together, e.g. in tests
+type MemoryNetwork struct {
+	sync.Mutex                           // lock members
+	members    map[*memoryTransport]bool // transports not yet closed
+}
+
+// NewMemoryNetwork returns a network with no transports attached
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{members: make(map[*memoryTransport]bool)}
+}
+
+// memoryTransport is a peer's attachment to a MemoryNetwork
+type memoryTransport struct {
+	network *MemoryNetwork
+	hostIp  string      // the address the peer is given
+	in      chan Packet // announcements sent to the peer, closed on Close
+}
+
+// Transport attaches a new peer to the network. Its announcements are given
+// hostIp as their HostIp and are delivered to every other peer attached.
+func (n *MemoryNetwork) Transport(hostIp string) Transport {
+	t := &memoryTransport{
+		network: n,
+		hostIp:  hostIp,
+		in:      make(chan Packet, memoryQueueSize)}
+
+	n.Lock()
+	n.members[t] = true
+	n.Unlock()
+	return t
+}
+
+func (t *memoryTransport) String() string {
+	return "memory " + t.hostIp
+}
+
+func (t *memoryTransport) Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error {
+	data, err := encode(t.hostIp)
+	if err != nil {
+		return err
+	}
+	packet := Packet{Data: data, From: &net.IPAddr{IP: net.ParseIP(t.hostIp)}}
+
+	t.network.Lock()
+	defer t.network.Unlock()
+	for peer := range t.network.members {
+		if peer == t {
+			continue
+		}
+		select {
+		case peer.in <- packet:
+		default:
+			l.Warn("Dropping announcement for %s, its queue is full", peer.hostIp)
+		}
+	}
+	return nil
+}
+
+func (t *memoryTransport) Receive(l log.Logger, packets chan<- Packet) {
+	for packet := range t.in {
+		packets <- packet
+	}
+}
+
+func (t *memoryTransport) State() TransportState {
+	return TransportState{Up: true, Detail: "in memory"}
+}
+
+func (t *memoryTransport) Close() error {
+	t.network.Lock()
+	defer t.network.Unlock()
+	if t.network.members[t] {
+		delete(t.network.members, t)
+		close(t.in)
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// groupConn is our membership of one multicast group
+type groupConn struct {
+	addr               *net.UDPAddr
+	recvConn, sendConn *net.UDPConn // UDP connections to allow us to send and receive change updates
+	hostIp             string       // our address as seen by members of the group
+}
+
+// udpNetwork returns the network to use for addr, so that each group is
+// joined in its own address family
+func udpNetwork(addr *net.UDPAddr) string {
+	if addr.IP.To4() != nil {
+		return "udp4"
+	}
+	return "udp6"
+}
+
+// establishConnPair joins the group at addr and returns connections to receive
+// from and send to it, along with our address as seen by its members.
+func establishConnPair(addr *net.UDPAddr, opts MulticastOptions) (recvConn, sendConn *net.UDPConn, hostIp net.IP, err error) {
+	var (
+		network = udpNetwork(addr)
+		ipv6    = network == "udp6"
+		iface   *net.Interface // interface to join on, nil for the system's choice
+		subnet  *net.IPNet     // subnet our address should be in, if any
+		laddr   *net.UDPAddr   // address to send from, nil for the system's choice
+	)
+
+	switch {
+	case opts.Interface != "":
+		if iface, subnet, err = ResolveInterface(opts.Interface); err != nil {
+			return
+		}
+	case addr.Zone != "":
+		if iface, err = net.InterfaceByName(addr.Zone); err != nil {
+			return
+		}
+	case ipv6 && addr.IP.IsLinkLocalMulticast():
+		if iface, err = multicastInterface(true); err != nil {
+			return
+		}
+	}
+
+	if iface != nil {
+		if ipv6 && addr.IP.IsLinkLocalMulticast() {
+			addr = &net.UDPAddr{IP: addr.IP, Port: addr.Port, Zone: iface.Name}
+		}
+
+		laddr = &net.UDPAddr{}
+		if laddr.IP, err = interfaceAddr(iface, ipv6, subnet); err != nil {
+			return
+		}
+		if laddr.IP.IsLinkLocalUnicast() {
+			laddr.Zone = iface.Name
+		}
+	}
+
+	if recvConn, err = net.ListenMulticastUDP(network, iface, addr); err != nil {
+		return
+	}
+
+	if sendConn, err = net.DialUDP(network, laddr, addr); err != nil {
+		recvConn.Close()
+		return
+	}
+
+	var (
+		ifIndex int
+		ifAddr  net.IP
+	)
+	if iface != nil {
+		ifIndex, ifAddr = iface.Index, laddr.IP
+	}
+	if err = setMulticastOptions(sendConn, ipv6, ifIndex, ifAddr, opts.TTL, opts.Loopback); err != nil {
+		recvConn.Close()
+		sendConn.Close()
+		return
+	}
+
+	hostIp = sendConn.LocalAddr().(*net.UDPAddr).IP
+	return
+}
+
+// joinGroups joins each group in addrs, skipping those that cannot be joined,
+// e.g. because the host has no address in that family.
+func joinGroups(l log.Logger, addrs []*net.UDPAddr, opts MulticastOptions) (groups []*groupConn) {
+	for _, addr := range addrs {
+		l.Info("Joining %v multicast(%t) group", addr, addr.IP.IsMulticast())
+		recvConn, sendConn, hostIp, err := establishConnPair(addr, opts)
+		if err != nil {
+			l.Error("Error joining %v: %s", addr, err)
+			continue
+		}
+
+		l.Info("Successfully joined %v multicast(%t) group on interface %s as %s",
+			addr, addr.IP.IsMulticast(), interfaceOf(hostIp), hostIp)
+		groups = append(groups, &groupConn{
+			addr:     addr,
+			recvConn: recvConn,
+			sendConn: sendConn,
+			hostIp:   hostIp.String()})
+	}
+	return groups
+}
+
+// multicastTransport sends announcements to multicast groups. It rejoins them
+// whenever the network changes, so that our address stays current.
+type multicastTransport struct {
+	addrs []*net.UDPAddr
+	opts  MulticastOptions
+	done  chan struct{} // closed on Close
+
+	sync.Mutex              // lock groups, err and closed
+	groups     []*groupConn // groups currently joined
+	err        error        // why no group could be joined, if none could
+	closed     bool
+}
+
+// NewMulticastTransport joins each group in addrs that can be joined. If none
+// can be, it keeps trying, as it does whenever the network changes.
+func NewMulticastTransport(l log.Logger, addrs []*net.UDPAddr, opts MulticastOptions) (Transport, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no multicast group to join")
+	}
+	if opts.Interface != "" {
+		if _, _, err := ResolveInterface(opts.Interface); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &multicastTransport{addrs: addrs, opts: opts, done: make(chan struct{})}
+	t.join(l)
+	return t, nil
+}
+
+// join joins the groups, leaving those joined before
+func (t *multicastTransport) join(l log.Logger) {
+	groups := joinGroups(l, t.addrs, t.opts)
+
+	t.Lock()
+	defer t.Unlock()
+	t.leave()
+	if t.closed {
+		for _, g := range groups {
+			g.recvConn.Close()
+			g.sendConn.Close()
+		}
+		return
+	}
+	t.groups, t.err = groups, nil
+	if len(groups) == 0 {
+		t.err = errors.New("could not join any multicast group")
+	}
+}
+
+// leave leaves the groups joined. It must be called with the lock held.
+func (t *multicastTransport) leave() {
+	for _, g := range t.groups {
+		g.recvConn.Close()
+		g.sendConn.Close()
+	}
+	t.groups = nil
+}
+
+func (t *multicastTransport) String() string {
+	return "multicast"
+}
+
+func (t *multicastTransport) State() TransportState {
+	t.Lock()
+	defer t.Unlock()
+	if len(t.groups) == 0 {
+		return TransportState{Detail: fmt.Sprint(t.err)}
+	}
+
+	var joined []string
+	for _, g := range t.groups {
+		joined = append(joined, fmt.Sprintf("%s as %s", g.addr, g.hostIp))
+	}
+	return TransportState{Up: true, Detail: "joined " + strings.Join(joined, ", ")}
+}
+
+// Send sends the announcement to every group, with HostIp set to our address
+// in the group's address family
+func (t *multicastTransport) Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error {
+	t.Lock()
+	defer t.Unlock()
+	if len(t.groups) == 0 {
+		return errors.New("no multicast group joined")
+	}
+
+	var lastErr error
+	sent := 0
+	for _, g := range t.groups {
+		data, err := encode(g.hostIp)
+		if err != nil {
+			return err
+		}
+
+		l.Fine("Sending %+v", data)
+		if _, err := g.sendConn.Write(data); err != nil {
+			l.Error("Cannot send to %s: %s", g.addr, err)
+			lastErr = err
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// Receive reads from the groups joined. It rejoins them when reading fails or
+// the network changes, with backoff until a read succeeds or the groups stay
+// joined for minJoinedTime, so a flapping interface is not rejoined in a tight
+// loop.
+func (t *multicastTransport) Receive(l log.Logger, packets chan<- Packet) {
+	var (
+		fingerprint = networkFingerprint()
+		retry       backoff
+	)
+	for {
+		t.Lock()
+		groups := t.groups
+		t.Unlock()
+
+		if len(groups) == 0 {
+			if !retry.wait(t.done) {
+				return
+			}
+			fingerprint = networkFingerprint()
+			t.join(l)
+			continue
+		}
+
+		var (
+			wg     sync.WaitGroup
+			broken = make(chan error, len(groups)) // read errors other than closing
+			heard  = make(chan struct{}, 1)        // holds a value once a read succeeds
+			joined = time.Now()
+		)
+		for _, g := range groups {
+			wg.Add(1)
+			go func(g *groupConn) {
+				defer wg.Done()
+				for {
+					b := make([]byte, 65536)
+
+					n, from, err := g.recvConn.ReadFromUDP(b)
+					if isClosed(err) {
+						return
+					} else if err != nil {
+						broken <- fmt.Errorf("cannot read from %s: %s", g.addr, err)
+						return
+					}
+					select {
+					case heard <- struct{}{}:
+					default:
+					}
+					packets <- Packet{Data: b[:n], From: from, Zone: from.Zone}
+				}
+			}(g)
+		}
+
+		// wait for a reason to rejoin
+		var (
+			ticker = time.NewTicker(netCheckInterval)
+			rejoin = false
+			failed = false
+		)
+		for !rejoin {
+			select {
+			case <-t.done:
+				ticker.Stop()
+				wg.Wait()
+				return
+			case err := <-broken:
+				l.Warn("Rejoining multicast groups: %s", err)
+				rejoin, failed = true, true
+			case <-ticker.C:
+				if now := networkFingerprint(); now != fingerprint {
+					l.Info("Network changed, rejoining multicast groups")
+					fingerprint, rejoin = now, true
+				}
+			}
+		}
+		ticker.Stop()
+
+		t.Lock()
+		t.leave()
+		t.Unlock()
+		wg.Wait()
+
+		select {
+		case <-heard:
+			retry.reset()
+		default:
+			if time.Since(joined) >= minJoinedTime {
+				retry.reset()
+			}
+		}
+		if failed && !retry.wait(t.done) {
+			return
+		}
+		t.join(l)
+	}
+}
+
+func (t *multicastTransport) Close() error {
+	t.Lock()
+	defer t.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+		t.leave()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package gitsync
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Defaults of MirrorNaming, giving gitsync-<user>-<peer id>-<branch>
+const (
+	DefaultMirrorPrefix   = "gitsync-"
+	DefaultMirrorTemplate = "{user}-{peer}-{branch}"
+)
+
+// MirrorNaming names the local branches peers' branches are fetched into.
+// Empty fields take the defaults.
+type MirrorNaming struct {
+	Prefix   string // starts every mirror branch, telling them apart from ours
+	Template string // rest of the name, in which {user}, {peer}, {repo} and {branch} are replaced
+}
+
+// Check reports templates that would not give each peer's branch a branch of
+// its own
+func (n MirrorNaming) Check() error {
+	if !strings.Contains(n.template(), "{branch}") {
+		return fmt.Errorf("mirror branch template %q has no {branch}", n.Template)
+	}
+	if !strings.Contains(n.template(), "{peer}") {
+		return fmt.Errorf("mirror branch template %q has no {peer}", n.Template)
+	}
+	return nil
+}
+
+func (n MirrorNaming) prefix() string {
+	if n.Prefix == "" {
+		return DefaultMirrorPrefix
+	}
+	return n.Prefix
+}
+
+func (n MirrorNaming) template() string {
+	if n.Template == "" {
+		return DefaultMirrorTemplate
+	}
+	return n.Template
+}
+
+// Branch is the name of the local branch the change's branch is fetched into
+func (n MirrorNaming) Branch(change GitChange) string {
+	return n.prefix() + strings.NewReplacer(
+		"{user}", change.User,
+		"{peer}", ShortPeerID(change.PeerID),
+		"{repo}", change.RepoName,
+		"{branch}", change.RefName).Replace(n.template())
+}
+
+// CheckRefName returns an error unless name, a branch name, follows git's
+// rules for ref names (see git check-ref-format). Names also must not start
+// with -, so that they cannot be taken for options by git. Names coming from
+// the network are checked before they reach git's command line.
+func CheckRefName(name string) error {
+	switch {
+	case name == "" || name == "@":
+		return fmt.Errorf("bad ref name %q", name)
+	case name[0] == '-':
+		return fmt.Errorf("ref name %q starts with -", name)
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.HasSuffix(name, "."):
+		return fmt.Errorf("ref name %q starts or ends badly", name)
+	case strings.Contains(name, "..") || strings.Contains(name, "//") || strings.Contains(name, "@{"):
+		return fmt.Errorf("ref name %q holds .., // or @{", name)
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return fmt.Errorf("ref name %q holds %q", name, r)
+		}
+	}
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("ref name %q has a component starting with . or ending with .lock", name)
+		}
+	}
+	return nil
+}
+
+// IsMirror reports whether branch is named like a mirror branch, so that it is
+// neither announced nor kept by cleanups
+func (n MirrorNaming) IsMirror(branch string) bool {
+	return strings.HasPrefix(branch, n.prefix())
+}
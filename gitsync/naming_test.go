@@ -0,0 +1,47 @@
+package gitsync
+
+import "testing"
+
+func TestCheckRefName(t *testing.T) {
+	for _, name := range []string{
+		"master",
+		"feature/login",
+		"gitsync-alice-aaaaaaaa-feature/login",
+		"v1.0",
+		"a-b_c+d",
+	} {
+		if err := CheckRefName(name); err != nil {
+			t.Errorf("CheckRefName(%q): %s", name, err)
+		}
+	}
+	for _, name := range []string{
+		"",
+		"@",
+		"-upload-pack=evil",
+		"--end-of-options",
+		"/master",
+		"master/",
+		"master.",
+		"a..b",
+		"a//b",
+		"a@{1}",
+		"a b",
+		"a~1",
+		"a^",
+		"a:b",
+		"a?",
+		"a*",
+		"a[b",
+		"a\\b",
+		"a\nb",
+		"a\x7f",
+		".hidden",
+		"a/.hidden",
+		"a.lock",
+		"a.lock/b",
+	} {
+		if err := CheckRefName(name); err == nil {
+			t.Errorf("CheckRefName(%q) succeeded", name)
+		}
+	}
+}
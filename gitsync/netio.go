@@ -2,9 +2,18 @@ package gitsync
 
 import (
 	"bytes"
+	"compress/flate"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	log "github.com/ngmoco/timber"
+	"io"
 	"net"
+	"strconv"
+	"time"
 )
 
 var (
@@ -24,97 +33,475 @@ func init() {
 	gob.Register(GitChange{})
 }
 
-func establishConnPair(addr *net.UDPAddr) (recvConn, sendConn *net.UDPConn, err error) {
-	if recvConn, err = net.ListenMulticastUDP("udp", nil, addr); err != nil {
-		return
+// NetConfig holds the settings for NetIO
+type NetConfig struct {
+	Transports  []Transport      // ways to reach peers, each announcement is sent through all of them
+	Channel     string           // only exchange announcements with peers on this channel
+	Gossip      bool             // forward peers' announcements received on one transport on the others
+	GossipTTL   int              // hops our announcements may be forwarded over, 0 for DefaultGossipTTL
+	MDNS        bool             // advertise ourselves and browse for peers with DNS-SD over mDNS
+	Multicast   MulticastOptions // how to join the mDNS groups
+	Auth        *Authenticator   // authenticates messages if not nil
+	FetchPort   int              // port of our encrypted fetch service, 0 if we serve plain git://
+	PeerID      string           // our installation's ID, see LoadPeerID
+	Identity    *Identity        // signs our announcements
+	KnownPeers  *KnownPeers      // keys pinned for each user, nil to accept any key
+	StrictPeers bool             // drop, rather than flag, announcements whose key does not match
+}
+
+// Payload formats, given by the first byte of the signed payload
+const (
+	payloadGob     = 0 // gob encoded []GitChange
+	payloadDeflate = 1 // the same, compressed with deflate
+)
+
+// encodeChanges produces the datagram announcing changes. The changes are
+// compressed, unless that does not make them smaller, before being signed
+// and, with cfg.Auth, sealed.
+func encodeChanges(cfg *NetConfig, changes []GitChange) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(payloadGob)
+	if err := gob.NewEncoder(buf).Encode(changes); err != nil {
+		return nil, err
 	}
 
-	if sendConn, err = net.DialUDP("udp", nil, addr); err != nil {
-		return
+	compressed := &bytes.Buffer{}
+	compressed.WriteByte(payloadDeflate)
+	w, _ := flate.NewWriter(compressed, flate.BestCompression)
+	w.Write(buf.Bytes()[1:])
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	payload := buf.Bytes()
+	if compressed.Len() < buf.Len() {
+		payload = compressed.Bytes()
 	}
 
-	return
+	data, err := cfg.Identity.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign message: %s", err)
+	}
+	if cfg.Auth != nil {
+		if data, err = cfg.Auth.Seal(data); err != nil {
+			return nil, fmt.Errorf("cannot authenticate message: %s", err)
+		}
+	}
+	return data, nil
 }
 
-// NetIO shares GitChanges on toNet with the network via a multicast group. It
-// will pass on GitChanges from the network via fromNet. It uniques the daemon
-// instance by changing the .Name member to be name@<host IP>/<original .Name)
-func NetIO(l log.Logger, repo Repo, addr *net.UDPAddr, fromNet, toNet chan GitChange) {
-	var (
-		err                error
-		recvConn, sendConn *net.UDPConn // UDP connections to allow us to send and	receive change updates
-	)
+// decodeChanges reverses encodeChanges, returning the changes and the key that
+// signed them
+func decodeChanges(cfg *NetConfig, data []byte) (changes []GitChange, key ed25519.PublicKey, err error) {
+	if cfg.Auth != nil {
+		if data, err = cfg.Auth.Open(data); err != nil {
+			return nil, nil, fmt.Errorf("unauthenticated message: %s", err)
+		}
+	}
 
-	l.Info("Joining %v multicast(%t) group", addr, addr.IP.IsMulticast())
-	if recvConn, sendConn, err = establishConnPair(addr); err != nil {
-		l.Critical("Error joining listening: %s\n", addr, err)
-		return
+	payload, key, err := OpenSigned(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unsigned message: %s", err)
+	}
+	if len(payload) == 0 {
+		return nil, nil, errors.New("empty message")
 	}
 
-	l.Info("Successfully joined %v multicast(%t) group", addr, addr.IP.IsMulticast())
-	defer recvConn.Close()
-	defer sendConn.Close()
-	hostIp := sendConn.LocalAddr().(*net.UDPAddr).IP.String()
+	var r io.Reader = bytes.NewReader(payload[1:])
+	switch payload[0] {
+	case payloadGob:
+	case payloadDeflate:
+		// bound the decompressed size, so a small message cannot exhaust memory
+		r = io.LimitReader(flate.NewReader(r), maxTCPFrameSize)
+	default:
+		return nil, nil, fmt.Errorf("unknown payload format %d", payload[0])
+	}
+	err = gob.NewDecoder(r).Decode(&changes)
+	return changes, key, err
+}
+
+// newChangeID returns a random identifier for an announcement
+func newChangeID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// sourceHost returns the host of addr, without the port, so that a peer's
+// announcements count together whichever port they come from
+func sourceHost(addr net.Addr) string {
+	if addr == nil {
+		return "unknown"
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
 
-	term := false
-	defer func() { term = true }()
-	rawFromNet := make(chan []byte, 128)
-	go func() {
-		for !term {
-			b := make([]byte, 1024)
+// DefaultGossipTTL is how many hops announcements may be forwarded over by
+// gossiping peers, unless set otherwise
+const DefaultGossipTTL = 3
 
-			if n, err := recvConn.Read(b); err != nil {
-				l.Critical("Cannot read socket: %s", err)
+// dedupeWindow is how long announcement IDs are remembered, to drop the copies
+// that arrive via each transport and group we are in
+const dedupeWindow = time.Minute
+
+// filter decides which of the announcements received are passed on. It sits
+// above the transports, so the same rules apply whichever way an announcement
+// arrived.
+type filter struct {
+	cfg    *NetConfig
+	repo   Repo
+	seen   map[string]time.Time // IDs of changes received recently
+	limits *peerLimits          // announcements accepted from each peer
+}
+
+func newFilter(cfg *NetConfig, repo Repo) *filter {
+	return &filter{
+		cfg:    cfg,
+		repo:   repo,
+		seen:   make(map[string]time.Time),
+		limits: newPeerLimits()}
+}
+
+// accept decodes the announcement in p, returning the changes it carries that
+// should be passed on, and whether it is a peer's announcement seen for the
+// first time, that gossiping peers may forward
+func (f *filter) accept(l log.Logger, p Packet) (accepted []GitChange, fresh bool) {
+	changes, key, err := decodeChanges(f.cfg, p.Data)
+	if err != nil {
+		l.Warn("Dropping message from %s: %s", p.From, err)
+		return nil, false
+	}
+	// keys are only vouched for by the shared secret or by being approved,
+	// anyone can send a new one
+	trusted := f.cfg.Auth != nil || (f.cfg.KnownPeers != nil && f.cfg.KnownPeers.Approved(key))
+	if p.Accepted != nil {
+		p.Accepted(trusted)
+	}
+
+	now := time.Now()
+	for id, t := range f.seen {
+		if now.Sub(t) > dedupeWindow {
+			delete(f.seen, id)
+		}
+	}
+	var unseen []GitChange
+	for _, change := range changes {
+		if _, dup := f.seen[change.ID]; dup {
+			l.Fine("Dropping duplicate of %s", change.ID)
+			continue
+		}
+		f.seen[change.ID] = now
+		unseen = append(unseen, change)
+	}
+	if len(unseen) == 0 {
+		return nil, false
+	}
+
+	// Only fresh announcements count towards the sender's rate, copies
+	// arriving through other transports and groups do not
+	peer := "key " + FormatKey(key)
+	if !trusted {
+		peer = "address " + sourceHost(p.From)
+	}
+	if ok, first := f.limits.allow(peer, now); !ok {
+		if first {
+			l.Warn("Peer with %s is announcing too often, dropping its announcements for now", peer)
+		}
+		l.Fine("Dropping announcement from %s, over the rate limit", p.From)
+		return nil, false
+	}
+
+	rootCommit, rootErr := f.repo.RootCommit()
+	if rootErr != nil {
+		l.Critical("Error getting root commit")
+	}
+	for _, change := range unseen {
+		l.Debug("received %+v", change)
+
+		// Link-local IPv6 addresses are only meaningful with the zone of the
+		// interface they were reached through
+		if ip := net.ParseIP(change.HostIp); ip != nil && ip.IsLinkLocalUnicast() && ip.To4() == nil {
+			change.HostIp = (&net.IPAddr{IP: ip, Zone: p.Zone}).String()
+		}
+
+		if f.isSelf(change) {
+			continue
+		}
+		fresh = true
+
+		if f.cfg.KnownPeers != nil {
+			peer := change.User
+			if change.PeerID != "" {
+				peer = fmt.Sprintf("%s (%s)", change.User, ShortPeerID(change.PeerID))
+			}
+			trust, err := f.cfg.KnownPeers.Check(change.PeerID, change.User, key)
+			if err != nil {
+				l.Error("Cannot check key of %s: %s", peer, err)
+			}
+			switch trust {
+			case TrustPinned:
+				l.Info("Pinned key %s for %s", FormatKey(key), peer)
+			case TrustRevoked:
+				l.Warn("Dropping announcement from %s signed with revoked key %s", peer, FormatKey(key))
 				continue
-			} else {
-				rawFromNet <- b[:n]
+			case TrustMismatch:
+				if f.cfg.StrictPeers {
+					l.Warn("Dropping announcement from %s signed with unknown key %s", peer, FormatKey(key))
+					continue
+				}
+				l.Warn("Announcement from %s is signed with unknown key %s", peer, FormatKey(key))
+				change.KeyMismatch = true
 			}
 		}
+
+		if rootErr == nil && rootCommit == change.RootCommit {
+			accepted = append(accepted, change)
+		}
+	}
+	return accepted, fresh
+}
+
+// isSelf reports whether change is one of ours. Peers too old to send a peer
+// ID are told apart by user name.
+func (f *filter) isSelf(change GitChange) bool {
+	if change.PeerID == "" {
+		return change.User == f.repo.User()
+	}
+	return change.PeerID == f.cfg.PeerID
+}
+
+// reportStates logs the transports whose state changed since last reported in
+// states, and whether any peer can still be reached
+func reportStates(l log.Logger, transports []*channelTransport, states []TransportState) {
+	changed, up := false, false
+	for i, t := range transports {
+		state := t.State()
+		up = up || state.Up
+		if state == states[i] {
+			continue
+		}
+		changed, states[i] = true, state
+
+		if state.Up {
+			l.Info("%s is up: %s", t, state.Detail)
+		} else {
+			l.Warn("%s is down: %s", t, state.Detail)
+		}
+	}
+	if changed && !up {
+		l.Warn("No transport can reach peers, changes will not be shared until the network is back")
+	}
+}
+
+// NetIO shares GitChanges on toNet with peers through the transports in cfg.
+// It will pass on GitChanges from peers via fromNet.
+// Announcements are tagged with cfg.Channel, and those of other channels are
+// dropped as they are received, before any decoding.
+// If cfg.Gossip is set, peers' announcements are re-sent, untouched, through
+// the transports other than the one they arrived through, as long as their TTL
+// allows. Announcements already seen are not forwarded again, so forwarding
+// cannot loop.
+// Changes made close together are sent in one announcement, compressed, with
+// changes to the same ref collapsed, and no more than sendRate announcements
+// are sent per second on average.
+// Each announcement is sent through every transport, with HostIp set to an
+// address the peers reached through it can reach us at. Changes that cannot be
+// sent through a transport are queued until it is back up. Copies of one change arriving
+// through several transports are only passed on once. Changes of other repos,
+// and our own, told apart by cfg.PeerID, are not passed on.
+// If cfg.MDNS is set, we advertise a ServiceType DNS-SD service, once the repo
+// has a commit, and browse for those of peers working on the same repo, adding
+// them to transports that keep a list of peers.
+// If cfg.Auth is not nil, every message is authenticated with it and messages
+// that fail authentication are dropped.
+// Every announcement is signed with cfg.Identity. The signer's key is checked
+// against cfg.KnownPeers and announcements signed with a revoked key are
+// dropped, as are those with a mismatched key when cfg.StrictPeers is set.
+// Otherwise they are passed on with KeyMismatch set. Peers announcing more
+// than peerRate times a second, on average, are dropped until they slow down.
+// Transports recover from network changes by themselves, their state is
+// logged as it changes. NetIO returns an error if it cannot start, and nil
+// once toNet is closed.
+func NetIO(l log.Logger, repo Repo, cfg NetConfig, fromNet, toNet chan GitChange) error {
+	if len(cfg.Transports) == 0 {
+		return errors.New("no transport to reach peers with")
+	}
+	if len(cfg.Channel) > maxChannelLen {
+		return fmt.Errorf("channel name is longer than %d bytes", maxChannelLen)
+	}
+
+	var (
+		transports  []*channelTransport // cfg.Transports, restricted to cfg.Channel
+		unicastPort = 0                 // port we listen on for unicast, for mDNS
+		ttl         = cfg.GossipTTL
+	)
+	if ttl == 0 {
+		ttl = DefaultGossipTTL
+	}
+	for _, t := range cfg.Transports {
+		if u, ok := t.(*unicast); ok {
+			unicastPort = u.opts.Port
+		}
+		transports = append(transports, withChannel(t, cfg.Channel, ttl))
+	}
+
+	packets := make(chan Packet, 128)
+	for _, t := range transports {
+		l.Info("Using %s", t)
+		defer t.Close()
+		go t.Receive(l, packets)
+	}
+
+	// advertise starts advertising over mDNS, returning false if the repo has
+	// no commit yet to tell it apart by
+	var (
+		discovered = make(chan Service)
+		md         *mdns
+	)
+	advertise := func() bool {
+		rootCommit, err := repo.RootCommit()
+		if err != nil {
+			return false
+		}
+		self := Service{
+			User:        repo.User(),
+			Repo:        rootCommit,
+			Channel:     cfg.Channel,
+			UnicastPort: unicastPort}
+		fetchPort := gitPort
+		if cfg.FetchPort != 0 {
+			fetchPort = cfg.FetchPort
+		}
+		md = newMDNS(cfg.Multicast, self, cfg.PeerID, repo.Name(), fetchPort)
+		go md.run(l, discovered)
+		return true
+	}
+	if cfg.MDNS && !advertise() {
+		l.Warn("Not advertising over mDNS until the repo has a commit")
+	}
+	defer func() {
+		if md != nil {
+			md.Close(l)
+		}
 	}()
 
+	// queues holds the changes waiting to be sent through each transport.
+	// Changes stay queued while the transport cannot reach anyone, and are
+	// sent, in order and collapsed to the latest state of each ref, once it
+	// can. They are not kept on disk: on startup PollDirectory announces
+	// every branch anyway.
+	queues := make([]batch, len(transports))
+
+	// flush sends the changes queued for transports[i], stopping at the
+	// first failure
+	flush := func(i int) {
+		t, queue := transports[i], &queues[i]
+		for !queue.empty() {
+			changes := queue.take()
+			encode := func(hostIp string) ([]byte, error) {
+				for j := range changes {
+					changes[j].HostIp = hostIp
+					l.Info("Sending %+v through %s", changes[j], t)
+				}
+				return encodeChanges(&cfg, changes)
+			}
+			if err := t.Send(l, encode); err != nil {
+				queue.putBack(changes)
+				l.Warn("Cannot send through %s, %d change(s) queued until it is back: %s", t, len(queue.changes), err)
+				return
+			}
+		}
+	}
+
+	var (
+		f       = newFilter(&cfg, repo)
+		states  = make([]TransportState, len(transports)) // last reported
+		ticker  = time.NewTicker(netCheckInterval)
+		pending batch                                 // changes waiting to be sent
+		sendDue <-chan time.Time                      // fires when pending may be sent, nil if it is empty
+		limiter = newRateLimiter(sendRate, sendBurst) // caps the announcements we send
+	)
+	defer ticker.Stop()
+	reportStates(l, transports, states)
+
 	for {
 		select {
+		case <-ticker.C:
+			reportStates(l, transports, states)
+			if cfg.MDNS && md == nil && advertise() {
+				l.Info("Repo has a commit, advertising over mDNS")
+			}
+			for i, t := range transports {
+				if !queues[i].empty() && states[i].Up {
+					l.Info("%s is back, sending %d queued change(s)", t, len(queues[i].changes))
+					flush(i)
+				}
+			}
+
 		case req, ok := <-toNet:
 			if !ok {
-				return
+				for i := range transports {
+					for _, change := range pending.changes {
+						queues[i].add(change)
+					}
+					flush(i)
+				}
+				return nil
 			}
 
+			req.PeerID = cfg.PeerID
 			req.User = repo.User()
-			req.HostIp = hostIp
-
-			l.Info("Sending %+v", req)
-			buf := &bytes.Buffer{}
-			enc := gob.NewEncoder(buf)
-
-			if err := enc.Encode(req); err != nil {
-				l.Critical("%s", err)
-				continue
+			req.FetchPort = cfg.FetchPort
+			req.ID = newChangeID()
+			pending.add(req)
+			if sendDue == nil {
+				sendDue = time.After(batchDelay)
 			}
 
-			l.Fine("Sending %+v", buf.Bytes())
-			if _, err := sendConn.Write(buf.Bytes()); err != nil {
-				l.Critical("%s", err)
+		case <-sendDue:
+			if d := limiter.delay(time.Now()); d > 0 {
+				l.Fine("Holding %d change(s) back for %s to keep under the send rate", len(pending.changes), d)
+				sendDue = time.After(d)
 				continue
 			}
+			limiter.allow(time.Now())
+			changes := pending.take()
+			for i := range transports {
+				for _, change := range changes {
+					queues[i].add(change)
+				}
+				flush(i)
+			}
 
-		case resp := <-rawFromNet:
-			var change GitChange
-			dec := gob.NewDecoder(bytes.NewReader(resp))
+			sendDue = nil
+			if !pending.empty() {
+				sendDue = time.After(limiter.delay(time.Now()))
+			}
 
-			if err := dec.Decode(&change); err != nil {
-				l.Critical("%s", err)
+		case svc := <-discovered:
+			l.Info("Discovered %s at %s, fetching from %s", svc.Instance, svc.Addr, svc.URL)
+			if svc.UnicastPort == 0 {
 				continue
-			} else {
-				l.Debug("received %+v", change)
+			}
+			for _, t := range transports {
+				t.AddPeer(net.JoinHostPort(svc.Addr, strconv.Itoa(svc.UnicastPort)))
 			}
 
-			if rootCommit, err := repo.RootCommit(); err != nil {
-				log.Critical("Error getting root commit")
-			} else {
-				if (repo.User() != change.User) && (rootCommit == change.RootCommit) {
-					fromNet <- change
+		case p := <-packets:
+			changes, fresh := f.accept(l, p)
+			if fresh && cfg.Gossip && p.TTL > 1 {
+				for _, t := range transports {
+					if t != p.via {
+						l.Debug("Forwarding announcement from %s to %s", p.From, t)
+						t.forward(l, p.Data, p.TTL-1)
+					}
 				}
 			}
+			for _, change := range changes {
+				fromNet <- change
+			}
 		}
 	}
 }
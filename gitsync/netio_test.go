@@ -0,0 +1,349 @@
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRepo is a Repo with no branches
+type testRepo struct {
+	user, root string
+}
+
+func (r testRepo) String() string                  { return r.Name() }
+func (r testRepo) Name() string                    { return "repo" }
+func (r testRepo) Path() string                    { return "/nonexistent/repo" }
+func (r testRepo) User() string                    { return r.user }
+func (r testRepo) Branches() ([]*GitChange, error) { return nil, nil }
+func (r testRepo) RootCommit() (string, error)     { return r.root, nil }
+
+// testPeer runs NetIO for one peer
+type testPeer struct {
+	fromNet, toNet chan GitChange
+}
+
+// startPeer runs NetIO for user, on the repo with root commit root, until the
+// test ends. Peers are given an identity and peer ID of their own.
+func startPeer(t *testing.T, user, root string, cfg NetConfig) *testPeer {
+	id, err := LoadIdentity(filepath.Join(t.TempDir(), "identity"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Identity = id
+	cfg.PeerID = user + "-id"
+
+	p := &testPeer{fromNet: make(chan GitChange, 16), toNet: make(chan GitChange)}
+	done := make(chan error, 1)
+	go func() {
+		done <- NetIO(log.Global, testRepo{user, root}, cfg, p.fromNet, p.toNet)
+	}()
+	t.Cleanup(func() {
+		close(p.toNet)
+		if err := <-done; err != nil {
+			t.Errorf("NetIO(%s): %s", user, err)
+		}
+	})
+	return p
+}
+
+// announce has the peer announce that ref moved to current
+func (p *testPeer) announce(ref, current string) {
+	p.toNet <- GitChange{RefName: ref, Current: current, RootCommit: "root"}
+}
+
+// expect waits for the peer to receive changes to refs, in order, and
+// returns them
+func (p *testPeer) expect(t *testing.T, refs ...string) (changes []GitChange) {
+	t.Helper()
+	for _, ref := range refs {
+		select {
+		case change := <-p.fromNet:
+			if change.RefName != ref {
+				t.Fatalf("received %s, want %s", change.RefName, ref)
+			}
+			changes = append(changes, change)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not received", ref)
+		}
+	}
+	return changes
+}
+
+// expectNothing checks that the peer receives nothing more for a while
+func (p *testPeer) expectNothing(t *testing.T) {
+	t.Helper()
+	select {
+	case change := <-p.fromNet:
+		t.Fatalf("received %+v", change)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestNetIODelivery(t *testing.T) {
+	t.Parallel()
+	var (
+		network = NewMemoryNetwork()
+		alice   = startPeer(t, "alice", "root", NetConfig{Transports: []Transport{network.Transport("192.0.2.1")}})
+		bob     = startPeer(t, "bob", "root", NetConfig{Transports: []Transport{network.Transport("192.0.2.2")}})
+		_       = startPeer(t, "carol", "other", NetConfig{Transports: []Transport{network.Transport("192.0.2.3")}})
+	)
+
+	alice.announce("master", "abc")
+	select {
+	case change := <-bob.fromNet:
+		if change.User != "alice" || change.PeerID != "alice-id" || change.HostIp != "192.0.2.1" || change.Current != "abc" {
+			t.Errorf("received %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("announcement not received")
+	}
+
+	// only the peers of the same repo pass the change on, and not its sender
+	alice.expectNothing(t)
+}
+
+func TestNetIOOtherRepo(t *testing.T) {
+	t.Parallel()
+	var (
+		network = NewMemoryNetwork()
+		alice   = startPeer(t, "alice", "root", NetConfig{Transports: []Transport{network.Transport("192.0.2.1")}})
+		carol   = startPeer(t, "carol", "other", NetConfig{Transports: []Transport{network.Transport("192.0.2.3")}})
+	)
+	alice.announce("master", "abc")
+	carol.expectNothing(t)
+}
+
+func TestNetIOChannels(t *testing.T) {
+	t.Parallel()
+	var (
+		network = NewMemoryNetwork()
+		alice   = startPeer(t, "alice", "root", NetConfig{Channel: "team", Transports: []Transport{network.Transport("192.0.2.1")}})
+		bob     = startPeer(t, "bob", "root", NetConfig{Channel: "team", Transports: []Transport{network.Transport("192.0.2.2")}})
+		eve     = startPeer(t, "eve", "root", NetConfig{Channel: "other", Transports: []Transport{network.Transport("192.0.2.3")}})
+	)
+	alice.announce("master", "abc")
+	bob.expect(t, "master")
+	eve.expectNothing(t)
+}
+
+func TestNetIOAuth(t *testing.T) {
+	t.Parallel()
+	var (
+		network = NewMemoryNetwork()
+		alice   = startPeer(t, "alice", "root", NetConfig{Auth: newAuthenticator(t, true, "team"), Transports: []Transport{network.Transport("192.0.2.1")}})
+		bob     = startPeer(t, "bob", "root", NetConfig{Auth: newAuthenticator(t, true, "team"), Transports: []Transport{network.Transport("192.0.2.2")}})
+		eve     = startPeer(t, "eve", "root", NetConfig{Auth: newAuthenticator(t, true, "guess"), Transports: []Transport{network.Transport("192.0.2.3")}})
+		plain   = startPeer(t, "plain", "root", NetConfig{Transports: []Transport{network.Transport("192.0.2.4")}})
+	)
+	alice.announce("master", "abc")
+	bob.expect(t, "master")
+	eve.expectNothing(t)
+	plain.expectNothing(t)
+}
+
+func TestNetIODedupe(t *testing.T) {
+	t.Parallel()
+	// alice and bob share two networks, each announcement reaches bob twice
+	var (
+		network1, network2 = NewMemoryNetwork(), NewMemoryNetwork()
+		alice              = startPeer(t, "alice", "root", NetConfig{Transports: []Transport{network1.Transport("192.0.2.1"), network2.Transport("198.51.100.1")}})
+		bob                = startPeer(t, "bob", "root", NetConfig{Transports: []Transport{network1.Transport("192.0.2.2"), network2.Transport("198.51.100.2")}})
+	)
+	alice.announce("master", "abc")
+	bob.expect(t, "master")
+	bob.expectNothing(t)
+}
+
+func TestNetIOGossip(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name     string
+		ttl      int
+		bridges  int
+		received bool
+	}{
+		{"one bridge", 0, 1, true},
+		{"two bridges", 0, 2, true},
+		{"TTL too short", 1, 1, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// alice is only on network1 and carol on network2, bridges are on both
+			var (
+				network1, network2 = NewMemoryNetwork(), NewMemoryNetwork()
+				alice              = startPeer(t, "alice", "root", NetConfig{GossipTTL: test.ttl, Transports: []Transport{network1.Transport("192.0.2.1")}})
+				carol              = startPeer(t, "carol", "root", NetConfig{Transports: []Transport{network2.Transport("198.51.100.3")}})
+				bridges            []*testPeer
+			)
+			for i := 0; i < test.bridges; i++ {
+				user := string(rune('p'+i)) + "bridge"
+				bridges = append(bridges, startPeer(t, user, "root", NetConfig{
+					Gossip:     true,
+					Transports: []Transport{network1.Transport("192.0.2.10"), network2.Transport("198.51.100.10")}}))
+			}
+
+			alice.announce("master", "abc")
+			if test.received {
+				// once, even though bridges forward each other's copies
+				carol.expect(t, "master")
+			}
+			carol.expectNothing(t)
+			for _, bridge := range bridges {
+				bridge.expect(t, "master")
+				bridge.expectNothing(t)
+			}
+			// and it never comes back to alice
+			alice.expectNothing(t)
+		})
+	}
+}
+
+// flakyTransport fails to send while down
+type flakyTransport struct {
+	Transport
+	sync.Mutex
+	down bool
+}
+
+func (t *flakyTransport) setDown(down bool) {
+	t.Lock()
+	t.down = down
+	t.Unlock()
+}
+
+func (t *flakyTransport) Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error {
+	t.Lock()
+	down := t.down
+	t.Unlock()
+	if down {
+		return errors.New("network is down")
+	}
+	return t.Transport.Send(l, encode)
+}
+
+func (t *flakyTransport) State() TransportState {
+	t.Lock()
+	defer t.Unlock()
+	return TransportState{Up: !t.down}
+}
+
+func TestNetIOOfflineQueue(t *testing.T) {
+	t.Parallel()
+	var (
+		network = NewMemoryNetwork()
+		flaky   = &flakyTransport{Transport: network.Transport("192.0.2.1"), down: true}
+		alice   = startPeer(t, "alice", "root", NetConfig{Transports: []Transport{flaky}})
+		bob     = startPeer(t, "bob", "root", NetConfig{Transports: []Transport{network.Transport("192.0.2.2")}})
+	)
+
+	// changes made while down are queued, a ref moving twice only once
+	alice.announce("master", "abc")
+	alice.announce("topic", "def")
+	time.Sleep(2 * batchDelay)
+	alice.announce("master", "ghi")
+	time.Sleep(2 * batchDelay)
+	bob.expectNothing(t)
+
+	// and sent, in order, with the next change once back up
+	flaky.setDown(false)
+	alice.announce("other", "jkl")
+	changes := bob.expect(t, "master", "topic", "other")
+	if changes[0].Current != "ghi" {
+		t.Errorf("master announced at %s, want ghi", changes[0].Current)
+	}
+	bob.expectNothing(t)
+}
+
+// signedPacket returns a packet from addr carrying a change to ref, signed with
+// a new key unless cfg holds an identity
+func signedPacket(t *testing.T, cfg *NetConfig, from net.Addr, ref string) Packet {
+	t.Helper()
+	if cfg.Identity == nil {
+		id, err := LoadIdentity(filepath.Join(t.TempDir(), "identity"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Identity = id
+	}
+	data, err := encodeChanges(cfg, []GitChange{{ID: newChangeID(), PeerID: ref + "-id", User: ref, RefName: ref, RootCommit: "root"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Packet{Data: data, From: from}
+}
+
+func TestFilterLimitsUnapprovedKeysByAddress(t *testing.T) {
+	kp, err := LoadKnownPeers(filepath.Join(t.TempDir(), "known_peers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		f    = newFilter(&NetConfig{KnownPeers: kp}, testRepo{"me", "root"})
+		from = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 9999}
+	)
+
+	// a new key per announcement, each pinned on first use, still counts
+	// against the sender's address
+	for i := 0; i < 10; i++ {
+		f.accept(log.Global, signedPacket(t, &NetConfig{}, from, fmt.Sprint("user", i)))
+	}
+	if _, ok := f.limits.limiters["address 192.0.2.1"]; !ok || len(f.limits.limiters) != 1 {
+		t.Errorf("limiting %v, want the sender's address only", f.limits.limiters)
+	}
+
+	// while an approved key has its own limit, wherever it sends from
+	var (
+		approved = &NetConfig{}
+		other    = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 9999}
+	)
+	f.accept(log.Global, signedPacket(t, approved, other, "alice"))
+	if n, err := kp.Approve("alice", ""); err != nil || n != 1 {
+		t.Fatalf("Approve(alice) = %d, %v, want 1 key", n, err)
+	}
+	f.accept(log.Global, signedPacket(t, approved, from, "alice"))
+	if _, ok := f.limits.limiters["key "+FormatKey(approved.Identity.PublicKey())]; !ok {
+		t.Errorf("limiting %v, want alice's key", f.limits.limiters)
+	}
+}
+
+func TestFilterTrustsApprovedKeys(t *testing.T) {
+	kp, err := LoadKnownPeers(filepath.Join(t.TempDir(), "known_peers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		f      = newFilter(&NetConfig{KnownPeers: kp}, testRepo{"me", "root"})
+		sender = &NetConfig{}
+		from   = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 9998}
+	)
+	accept := func() (trusted bool) {
+		p := signedPacket(t, sender, from, "mallory")
+		p.Accepted = func(ok bool) { trusted = ok }
+		f.accept(log.Global, p)
+		return trusted
+	}
+
+	// a key pinned on first use is not trusted, however often it is used
+	if accept() || accept() {
+		t.Errorf("unknown key trusted")
+	}
+	if _, err = kp.Approve("mallory", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !accept() {
+		t.Errorf("approved key not trusted")
+	}
+
+	// with a shared secret, every announcement that decodes is
+	auth := newAuthenticator(t, false, "secret")
+	f = newFilter(&NetConfig{Auth: auth}, testRepo{"me", "root"})
+	sender = &NetConfig{Auth: auth}
+	if !accept() {
+		t.Errorf("authenticated announcement not trusted")
+	}
+}
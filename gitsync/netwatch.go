@@ -0,0 +1,70 @@
+package gitsync
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	netCheckInterval = 5 * time.Second // how often interfaces are checked for changes
+	minBackoff       = time.Second     // first wait before retrying to reach the network
+	maxBackoff       = time.Minute     // longest wait before retrying to reach the network
+	minJoinedTime    = time.Minute     // how long groups must stay joined, if nothing is read, for the backoff to start over
+)
+
+// networkFingerprint summarises the interfaces that are up and their
+// addresses, so that changes to them, e.g. joining another Wi-Fi network or
+// waking from sleep, can be noticed
+func networkFingerprint() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	var parts []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			parts = append(parts, fmt.Sprintf("%s/%s", iface.Name, a))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// backoff spaces out attempts to reach the network, doubling the wait after
+// each failure up to maxBackoff
+type backoff struct {
+	next time.Duration
+}
+
+// wait sleeps until the next attempt is due, returning false if done is closed
+// first
+func (b *backoff) wait(done <-chan struct{}) bool {
+	if b.next == 0 {
+		b.next = minBackoff
+	}
+	select {
+	case <-done:
+		return false
+	case <-time.After(b.next):
+	}
+	if b.next *= 2; b.next > maxBackoff {
+		b.next = maxBackoff
+	}
+	return true
+}
+
+// reset starts the waits over after a success
+func (b *backoff) reset() {
+	b.next = 0
+}
@@ -0,0 +1,57 @@
+package gitsync
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shortPeerIDLen is how much of a peer ID is used in mirror branch names
+const shortPeerIDLen = 8
+
+// LoadPeerID reads the peer ID stored at path, generating and saving a new one
+// if the file does not exist. The peer ID tells installations apart, whatever
+// their user names.
+func LoadPeerID(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newPeerID(path)
+	} else if err != nil {
+		return "", err
+	}
+
+	id := strings.TrimSpace(string(data))
+	if id == "" || strings.ContainsAny(id, " \t\n/") {
+		return "", fmt.Errorf("%s does not hold a valid peer ID", path)
+	}
+	return id, nil
+}
+
+// newPeerID generates a peer ID and saves it to path
+func newPeerID(path string) (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, []byte(id+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ShortPeerID abbreviates a peer ID for display
+func ShortPeerID(id string) string {
+	if len(id) > shortPeerIDLen {
+		return id[:shortPeerIDLen]
+	}
+	return id
+}
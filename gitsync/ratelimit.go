@@ -0,0 +1,151 @@
+package gitsync
+
+import (
+	"time"
+)
+
+const (
+	batchDelay = 250 * time.Millisecond // how long changes are held to be sent together
+	maxBatch   = 64                     // most changes sent in one announcement
+
+	sendRate  = 2 // announcements we send per second, on average
+	sendBurst = 5 // announcements we may send back to back
+
+	peerRate     = 10              // announcements accepted from one peer per second, on average
+	peerBurst    = 40              // announcements accepted from one peer back to back
+	peerIdleTime = 5 * time.Minute // how long a quiet peer's limiter is kept
+	maxLimiters  = 1024            // most peers rate limited at once
+)
+
+// rateLimiter is a token bucket, allowing burst events at once and rate events
+// per second on average
+type rateLimiter struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time // when tokens was last refilled
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// refill adds the tokens earned since last refilled
+func (r *rateLimiter) refill(now time.Time) {
+	if now.Before(r.last) {
+		return
+	}
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+}
+
+// allow reports whether an event may happen now, and if so counts it
+func (r *rateLimiter) allow(now time.Time) bool {
+	r.refill(now)
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// delay returns how long until an event will be allowed
+func (r *rateLimiter) delay(now time.Time) time.Duration {
+	r.refill(now)
+	if r.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+}
+
+// batch collects the changes to send in the next announcements. A change to a
+// ref already in the batch replaces it, keeping the earlier Prev, so a ref
+// that moves several times in a row, or while the network is down, is
+// announced once.
+type batch struct {
+	changes []GitChange
+}
+
+func (b *batch) add(change GitChange) {
+	for i, queued := range b.changes {
+		if queued.RefName == change.RefName {
+			change.Prev = queued.Prev
+			b.changes[i] = change
+			return
+		}
+	}
+	b.changes = append(b.changes, change)
+}
+
+// take removes and returns up to maxBatch changes, oldest first
+func (b *batch) take() []GitChange {
+	n := len(b.changes)
+	if n > maxBatch {
+		n = maxBatch
+	}
+	taken := b.changes[:n:n]
+	b.changes = b.changes[n:]
+	return taken
+}
+
+// putBack returns changes taken but not sent to the front of the batch. Changes
+// to the same refs added since replace them.
+func (b *batch) putBack(changes []GitChange) {
+	newer := b.changes
+	b.changes = nil
+	for _, change := range changes {
+		b.add(change)
+	}
+	for _, change := range newer {
+		b.add(change)
+	}
+}
+
+func (b *batch) empty() bool {
+	return len(b.changes) == 0
+}
+
+// peerLimits rate limits the announcements accepted from each peer. Peers are
+// told apart by their trusted key, or by address until their key is trusted,
+// as a sender picks its key and could otherwise pick a new one each time.
+type peerLimits struct {
+	limiters map[string]*peerLimiter // peer -> limiter
+}
+
+type peerLimiter struct {
+	*rateLimiter
+	limited bool // whether the last announcement was refused
+}
+
+func newPeerLimits() *peerLimits {
+	return &peerLimits{limiters: make(map[string]*peerLimiter)}
+}
+
+// allow reports whether an announcement from peer may be accepted now, and
+// whether this is the first refused since the peer was last allowed. Limiters
+// of peers quiet for peerIdleTime are dropped, and while maxLimiters peers are
+// being limited, announcements from others are refused.
+func (pl *peerLimits) allow(peer string, now time.Time) (ok, first bool) {
+	for k, limiter := range pl.limiters {
+		if now.Sub(limiter.last) > peerIdleTime {
+			delete(pl.limiters, k)
+		}
+	}
+
+	limiter, found := pl.limiters[peer]
+	if !found {
+		if len(pl.limiters) >= maxLimiters {
+			return false, false
+		}
+		limiter = &peerLimiter{rateLimiter: newRateLimiter(peerRate, peerBurst)}
+		pl.limiters[peer] = limiter
+	}
+	if limiter.allow(now) {
+		limiter.limited = false
+		return true, false
+	}
+	first, limiter.limited = !limiter.limited, true
+	return false, first
+}
@@ -0,0 +1,147 @@
+package gitsync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPeerLimits(t *testing.T) {
+	var (
+		pl  = newPeerLimits()
+		now = time.Now()
+	)
+	for i := 0; i < peerBurst; i++ {
+		if ok, _ := pl.allow("a", now); !ok {
+			t.Fatalf("announcement %d refused within the burst", i)
+		}
+	}
+	if ok, first := pl.allow("a", now); ok || !first {
+		t.Errorf("allow over the burst = %t, %t, want refused for the first time", ok, first)
+	}
+	if ok, first := pl.allow("a", now); ok || first {
+		t.Errorf("allow over the burst again = %t, %t, want refused", ok, first)
+	}
+
+	// peers are limited separately
+	if ok, _ := pl.allow("b", now); !ok {
+		t.Errorf("other peer refused")
+	}
+
+	// and tokens come back with time
+	if ok, _ := pl.allow("a", now.Add(time.Second)); !ok {
+		t.Errorf("refused after a second")
+	}
+}
+
+func TestPeerLimitsExpire(t *testing.T) {
+	var (
+		pl  = newPeerLimits()
+		now = time.Now()
+	)
+	for i := 0; i < maxLimiters; i++ {
+		pl.allow(fmt.Sprint(i), now)
+	}
+
+	// a full table refuses new peers, but not those it holds
+	if ok, _ := pl.allow("new", now); ok {
+		t.Errorf("new peer allowed with %d peers limited", len(pl.limiters))
+	}
+	if ok, _ := pl.allow("0", now); !ok {
+		t.Errorf("known peer refused")
+	}
+
+	// until idle peers are dropped
+	later := now.Add(peerIdleTime + time.Second)
+	if ok, _ := pl.allow("new", later); !ok {
+		t.Errorf("new peer refused once the others were idle")
+	}
+	if len(pl.limiters) != 1 {
+		t.Errorf("%d limiters kept, want 1", len(pl.limiters))
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var b batch
+	b.add(GitChange{RefName: "master", Prev: "a", Current: "b"})
+	b.add(GitChange{RefName: "topic", Prev: "x", Current: "y"})
+	b.add(GitChange{RefName: "master", Prev: "b", Current: "c"})
+
+	// a ref moving twice is announced once, from where it was to where it is
+	changes := b.take()
+	want := []GitChange{
+		{RefName: "master", Prev: "a", Current: "c"},
+		{RefName: "topic", Prev: "x", Current: "y"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("take() = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("take()[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+	if !b.empty() {
+		t.Errorf("batch not empty once taken")
+	}
+}
+
+func TestBatchTakeMax(t *testing.T) {
+	var b batch
+	for i := 0; i < maxBatch+1; i++ {
+		b.add(GitChange{RefName: fmt.Sprint(i)})
+	}
+	if n := len(b.take()); n != maxBatch {
+		t.Errorf("took %d changes, want %d", n, maxBatch)
+	}
+	if changes := b.take(); len(changes) != 1 || changes[0].RefName != fmt.Sprint(maxBatch) {
+		t.Errorf("then took %+v, want the last change", changes)
+	}
+}
+
+func TestBatchPutBack(t *testing.T) {
+	var b batch
+	b.add(GitChange{RefName: "master", Prev: "a", Current: "b"})
+	b.add(GitChange{RefName: "topic", Prev: "x", Current: "y"})
+	unsent := b.take()
+
+	// changes made while the unsent ones were out are merged with them
+	b.add(GitChange{RefName: "topic", Prev: "y", Current: "z"})
+	b.add(GitChange{RefName: "other", Prev: "1", Current: "2"})
+	b.putBack(unsent)
+
+	changes := b.take()
+	want := []GitChange{
+		{RefName: "master", Prev: "a", Current: "b"},
+		{RefName: "topic", Prev: "x", Current: "z"},
+		{RefName: "other", Prev: "1", Current: "2"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("take() = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("take()[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestRateLimiterDelay(t *testing.T) {
+	var (
+		r   = newRateLimiter(2, 1)
+		now = r.last
+	)
+	if d := r.delay(now); d != 0 {
+		t.Errorf("delay with a token = %s, want 0", d)
+	}
+	r.allow(now)
+	if d := r.delay(now); d != 500*time.Millisecond {
+		t.Errorf("delay without tokens = %s, want 500ms", d)
+	}
+	if r.allow(now.Add(250 * time.Millisecond)) {
+		t.Errorf("allowed before the delay")
+	}
+	if !r.allow(now.Add(500 * time.Millisecond)) {
+		t.Errorf("refused after the delay")
+	}
+}
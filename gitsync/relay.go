@@ -0,0 +1,301 @@
+package gitsync
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"net"
+	"sync"
+	"time"
+)
+
+// relayQueueSize is how many frames are queued for a daemon before the relay
+// drops them
+const relayQueueSize = 128
+
+// relayHello is the first frame a daemon sends to a relay. The relay only
+// forwards announcements between daemons that sent the same Channel and Repo.
+type relayHello struct {
+	Channel string // channel the daemon is on
+	Repo    string // root commit of the daemon's repo
+}
+
+// relayPeer is a daemon connected to a relay
+type relayPeer struct {
+	conn net.Conn
+	out  chan []byte // frames to send to the daemon
+}
+
+// ServeRelay accepts connections from daemons on listener and forwards each
+// announcement a daemon sends to the other daemons connected for the same
+// channel and repo.
+// Announcements are forwarded untouched, so the relay needs none of the team's
+// secrets. It returns when listener fails.
+func ServeRelay(l log.Logger, listener net.Listener) error {
+	var (
+		lock  sync.Mutex
+		repos = make(map[relayHello]map[*relayPeer]bool) // channel and root commit -> daemons
+	)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			conn.SetReadDeadline(time.Now().Add(unicastTimeout))
+			var hello relayHello
+			raw, err := readFrame(conn)
+			if err == nil {
+				err = gob.NewDecoder(bytes.NewReader(raw)).Decode(&hello)
+			}
+			if err == nil && hello.Repo == "" {
+				err = errors.New("no repo given")
+			}
+			if err != nil {
+				l.Warn("Bad hello from %s: %s", conn.RemoteAddr(), err)
+				return
+			}
+			conn.SetReadDeadline(time.Time{})
+
+			peer := &relayPeer{conn: conn, out: make(chan []byte, relayQueueSize)}
+			lock.Lock()
+			if repos[hello] == nil {
+				repos[hello] = make(map[*relayPeer]bool)
+			}
+			repos[hello][peer] = true
+			l.Info("%s joined repo %s on channel %q, %d daemon(s) connected", conn.RemoteAddr(), hello.Repo, hello.Channel, len(repos[hello]))
+			lock.Unlock()
+
+			defer func() {
+				lock.Lock()
+				delete(repos[hello], peer)
+				if len(repos[hello]) == 0 {
+					delete(repos, hello)
+				}
+				lock.Unlock()
+				close(peer.out)
+				l.Info("%s left repo %s", conn.RemoteAddr(), hello.Repo)
+			}()
+
+			go func() {
+				failed := false
+				for frame := range peer.out {
+					if failed {
+						continue
+					}
+					conn.SetWriteDeadline(time.Now().Add(unicastTimeout))
+					if err := writeFrame(conn, frame); err != nil {
+						l.Warn("Cannot send to %s: %s", conn.RemoteAddr(), err)
+						// the reader notices and drops the daemon
+						conn.Close()
+						failed = true
+					}
+				}
+			}()
+
+			frames := make(chan []byte)
+			go func() {
+				defer close(frames)
+				if err := readTCPFrames(conn, frames); err != nil {
+					l.Warn("Error reading from %s: %s", conn.RemoteAddr(), err)
+				}
+			}()
+
+			for frame := range frames {
+				lock.Lock()
+				for other := range repos[hello] {
+					if other == peer {
+						continue
+					}
+					select {
+					case other.out <- frame:
+					default:
+						l.Warn("Dropping announcement for %s, it is not keeping up", other.conn.RemoteAddr())
+					}
+				}
+				lock.Unlock()
+			}
+		}(conn)
+	}
+}
+
+// relayClient keeps a connection to a relay open, reconnecting when it drops
+// or the network changes
+type relayClient struct {
+	addr  string        // host:port of the relay
+	hello relayHello    // introduces us to the relay
+	done  chan struct{} // closed on Close
+
+	sync.Mutex          // lock conn, err and closed
+	conn       net.Conn // nil while disconnected
+	err        error    // why we are disconnected
+	closed     bool
+}
+
+// NewRelayTransport sends announcements through the relay at addr, which
+// passes them on to the other daemons connected to it for channel and repo,
+// the root commit of our repo. Peers fetch from the address we reach the relay
+// from, see Send.
+func NewRelayTransport(addr, channel, repo string) Transport {
+	return &relayClient{
+		addr:  addr,
+		hello: relayHello{Channel: channel, Repo: repo},
+		done:  make(chan struct{}),
+		err:   errors.New("not connected yet")}
+}
+
+func (r *relayClient) String() string {
+	return "relay " + r.addr
+}
+
+func (r *relayClient) State() TransportState {
+	r.Lock()
+	defer r.Unlock()
+	if r.conn == nil {
+		return TransportState{Detail: r.err.Error()}
+	}
+	return TransportState{Up: true, Detail: "connected as " + r.conn.LocalAddr().String()}
+}
+
+func (r *relayClient) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	if r.conn != nil {
+		return r.conn.Close()
+	}
+	return nil
+}
+
+// setConn records the connection to the relay, or why there is none
+func (r *relayClient) setConn(conn net.Conn, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.conn, r.err = conn, err
+	if r.closed && conn != nil {
+		conn.Close()
+	}
+}
+
+// Receive connects to the relay and passes the announcements it forwards on
+// to packets. It reconnects, with backoff, whenever the connection drops, and
+// when the network changes so that our address stays current.
+func (r *relayClient) Receive(l log.Logger, packets chan<- Packet) {
+	var retry backoff
+	for {
+		conn, err := r.connect()
+		if err != nil {
+			l.Error("Cannot connect to relay %s: %s", r.addr, err)
+			r.setConn(nil, err)
+			if !retry.wait(r.done) {
+				return
+			}
+			continue
+		}
+		l.Info("Connected to relay %s as %s", r.addr, conn.LocalAddr())
+		retry.reset()
+		r.setConn(conn, nil)
+
+		var (
+			frames  = make(chan []byte)
+			readErr error
+			stop    = make(chan struct{})
+		)
+		go func() {
+			readErr = readTCPFrames(conn, frames)
+			close(frames)
+		}()
+		go func() {
+			// a connection made over an interface that went away can linger
+			// for a long time, drop it as soon as the network changes
+			fingerprint := networkFingerprint()
+			ticker := time.NewTicker(netCheckInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					if networkFingerprint() != fingerprint {
+						l.Info("Network changed, reconnecting to relay %s", r.addr)
+						conn.Close()
+						return
+					}
+				}
+			}
+		}()
+
+		from := conn.RemoteAddr().(*net.TCPAddr)
+		for data := range frames {
+			packets <- Packet{Data: data, From: from}
+		}
+		close(stop)
+		conn.Close()
+
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+		if readErr == nil {
+			readErr = errors.New("connection closed")
+		}
+		l.Warn("Lost connection to relay %s: %s", r.addr, readErr)
+		r.setConn(nil, readErr)
+		if !retry.wait(r.done) {
+			return
+		}
+	}
+}
+
+// connect dials the relay and introduces us
+func (r *relayClient) connect() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", r.addr, unicastTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err = gob.NewEncoder(buf).Encode(r.hello); err == nil {
+		conn.SetWriteDeadline(time.Now().Add(unicastTimeout))
+		err = writeFrame(conn, buf.Bytes())
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Send passes an announcement to the relay, with HostIp set to the address we
+// reach the relay from, the local address of our connection to it. Peers
+// fetch from that address, which they cannot reach if we are behind NAT.
+func (r *relayClient) Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.conn == nil {
+		return fmt.Errorf("not connected to relay %s", r.addr)
+	}
+
+	msg, err := encode(r.conn.LocalAddr().(*net.TCPAddr).IP.String())
+	if err != nil {
+		return err
+	}
+	r.conn.SetWriteDeadline(time.Now().Add(unicastTimeout))
+	if err = writeFrame(r.conn, msg); err != nil {
+		// the reader notices and reconnects
+		r.conn.Close()
+		return fmt.Errorf("cannot send to relay %s: %s", r.addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package gitsync
+
+import (
+	log "github.com/ngmoco/timber"
+	"net"
+	"testing"
+	"time"
+)
+
+// startRelay runs a relay on loopback until the test ends, returning its
+// address
+func startRelay(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go ServeRelay(log.Global, listener)
+	return listener.Addr().String()
+}
+
+// waitUp waits for t to be up
+func waitUp(t *testing.T, tr Transport) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !tr.State().Up; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is not up: %s", tr, tr.State().Detail)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// connectRelay connects a relay transport to addr, waiting until it is up
+func connectRelay(t *testing.T, addr, channel, repo string) (Transport, <-chan Packet) {
+	tr := NewRelayTransport(addr, channel, repo)
+	packets := make(chan Packet, 16)
+	go tr.Receive(log.Global, packets)
+	t.Cleanup(func() { tr.Close() })
+	waitUp(t, tr)
+	return tr, packets
+}
+
+func TestRelay(t *testing.T) {
+	var (
+		addr              = startRelay(t)
+		alice, _          = connectRelay(t, addr, "", "root")
+		_, toBob          = connectRelay(t, addr, "", "root")
+		_, toOtherRepo    = connectRelay(t, addr, "", "other")
+		_, toOtherChannel = connectRelay(t, addr, "team", "root")
+		hostIp            string
+	)
+
+	// the relay may not have registered every daemon yet when they are up
+	time.Sleep(100 * time.Millisecond)
+	err := alice.Send(log.Global, func(ip string) ([]byte, error) {
+		hostIp = ip
+		return []byte("announcement"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostIp != "127.0.0.1" {
+		t.Errorf("HostIp = %s, want the address we reach the relay from", hostIp)
+	}
+
+	select {
+	case p := <-toBob:
+		if string(p.Data) != "announcement" {
+			t.Errorf("received %q", p.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("announcement not relayed")
+	}
+
+	select {
+	case p := <-toOtherRepo:
+		t.Errorf("announcement relayed to another repo: %q", p.Data)
+	case p := <-toOtherChannel:
+		t.Errorf("announcement relayed to another channel: %q", p.Data)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestNetIORelay(t *testing.T) {
+	t.Parallel()
+	var (
+		addr  = startRelay(t)
+		alice = NewRelayTransport(addr, "", "root")
+		bob   = NewRelayTransport(addr, "", "root")
+		// NetIO connects the transports and closes them when done
+		alicePeer = startPeer(t, "alice", "root", NetConfig{Transports: []Transport{alice}})
+		bobPeer   = startPeer(t, "bob", "root", NetConfig{Transports: []Transport{bob}})
+	)
+	waitUp(t, alice)
+	waitUp(t, bob)
+	time.Sleep(100 * time.Millisecond)
+
+	alicePeer.announce("master", "abc")
+	change := bobPeer.expect(t, "master")[0]
+	if change.User != "alice" || change.HostIp != "127.0.0.1" {
+		t.Errorf("received %+v", change)
+	}
+}
@@ -0,0 +1,176 @@
+package gitsync
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+const (
+	saltSize     = 32
+	keyIDSize    = 8
+	maxFrameSize = 16 * 1024 // largest plaintext sent in one frame
+)
+
+// secureConn encrypts a stream connection with AES-GCM. Each direction has its
+// own key, derived from a team key and random salts picked by both ends, so a
+// recorded session cannot be replayed. Data is sent in length prefixed frames
+// whose nonce is a per-direction counter.
+type secureConn struct {
+	net.Conn
+	send, recv       cipher.AEAD
+	sendSeq, recvSeq uint64
+	pending          []byte // decrypted data not yet returned by Read
+}
+
+// keyID returns a short identifier for key that does not reveal it, so a
+// server holding several keys knows which one the client uses.
+func keyID(key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte("gitsync key id"))
+	return h.Sum(nil)[:keyIDSize]
+}
+
+// sessionAEAD derives the cipher for one direction of a session
+func sessionAEAD(key []byte, direction string, clientSalt, serverSalt []byte) (cipher.AEAD, error) {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(direction))
+	h.Write(clientSalt)
+	h.Write(serverSalt)
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newSecureConn(conn net.Conn, key []byte, client bool, clientSalt, serverSalt []byte) (*secureConn, error) {
+	c2s, err := sessionAEAD(key, "client to server", clientSalt, serverSalt)
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := sessionAEAD(key, "server to client", clientSalt, serverSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	if client {
+		return &secureConn{Conn: conn, send: c2s, recv: s2c}, nil
+	}
+	return &secureConn{Conn: conn, send: s2c, recv: c2s}, nil
+}
+
+// SecureClient encrypts conn with key. The server must hold the same key for
+// any data to get through.
+func SecureClient(conn net.Conn, key []byte) (net.Conn, error) {
+	hello := make([]byte, keyIDSize+saltSize)
+	copy(hello, keyID(key))
+	if _, err := rand.Read(hello[keyIDSize:]); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(hello); err != nil {
+		return nil, err
+	}
+
+	serverSalt := make([]byte, saltSize)
+	if _, err := io.ReadFull(conn, serverSalt); err != nil {
+		return nil, err
+	}
+
+	return newSecureConn(conn, key, true, hello[keyIDSize:], serverSalt)
+}
+
+// SecureServer accepts an encrypted connection from SecureClient using any of
+// keys.
+func SecureServer(conn net.Conn, keys [][]byte) (net.Conn, error) {
+	hello := make([]byte, keyIDSize+saltSize)
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	for _, k := range keys {
+		if bytes.Equal(keyID(k), hello[:keyIDSize]) {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return nil, errors.New("client uses an unknown key")
+	}
+
+	serverSalt := make([]byte, saltSize)
+	if _, err := rand.Read(serverSalt); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(serverSalt); err != nil {
+		return nil, err
+	}
+
+	return newSecureConn(conn, key, false, hello[keyIDSize:], serverSalt)
+}
+
+func seqNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+func (c *secureConn) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxFrameSize {
+			chunk = chunk[:maxFrameSize]
+		}
+
+		sealed := c.send.Seal(nil, seqNonce(c.send, c.sendSeq), chunk, nil)
+		c.sendSeq++
+
+		frame := make([]byte, 4+len(sealed))
+		binary.BigEndian.PutUint32(frame, uint32(len(sealed)))
+		copy(frame[4:], sealed)
+		if _, err = c.Conn.Write(frame); err != nil {
+			return n, err
+		}
+
+		n += len(chunk)
+		b = b[len(chunk):]
+	}
+	return n, nil
+}
+
+func (c *secureConn) Read(b []byte) (n int, err error) {
+	for len(c.pending) == 0 {
+		var header [4]byte
+		if _, err = io.ReadFull(c.Conn, header[:]); err != nil {
+			return 0, err
+		}
+
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxFrameSize+uint32(c.recv.Overhead()) {
+			return 0, fmt.Errorf("frame of %d bytes is too large", size)
+		}
+
+		sealed := make([]byte, size)
+		if _, err = io.ReadFull(c.Conn, sealed); err != nil {
+			return 0, err
+		}
+
+		if c.pending, err = c.recv.Open(nil, seqNonce(c.recv, c.recvSeq), sealed, nil); err != nil {
+			return 0, errors.New("cannot decrypt frame")
+		}
+		c.recvSeq++
+	}
+
+	n = copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
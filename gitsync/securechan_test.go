@@ -0,0 +1,103 @@
+package gitsync
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+// securePair returns the ends of a connection secured with clientKey by the
+// client, and any of serverKeys by the server
+func securePair(t *testing.T, clientKey []byte, serverKeys [][]byte) (client, server net.Conn, serverErr <-chan error) {
+	c, s := net.Pipe()
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+
+	errs := make(chan error, 1)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := SecureServer(s, serverKeys)
+		if err != nil {
+			s.Close() // so that the client is not left waiting
+		}
+		errs <- err
+		accepted <- conn
+	}()
+
+	client, err := SecureClient(c, clientKey)
+	if err != nil {
+		return nil, nil, errs
+	}
+	if err = <-errs; err != nil {
+		errs <- err
+		return client, nil, errs
+	}
+	return client, <-accepted, errs
+}
+
+func TestSecureConn(t *testing.T) {
+	client, server, _ := securePair(t, []byte("team"), [][]byte{[]byte("other"), []byte("team")})
+	if server == nil {
+		t.Fatal("server refused the client's key")
+	}
+
+	// more than a frame, in both directions
+	sent := bytes.Repeat([]byte("gitsync "), 3*maxFrameSize/8+1)
+	for _, dir := range []struct {
+		name     string
+		from, to net.Conn
+	}{
+		{"client to server", client, server},
+		{"server to client", server, client},
+	} {
+		go dir.from.Write(sent)
+		got := make([]byte, len(sent))
+		if _, err := io.ReadFull(dir.to, got); err != nil {
+			t.Fatalf("%s: %s", dir.name, err)
+		}
+		if !bytes.Equal(got, sent) {
+			t.Errorf("%s: data differs", dir.name)
+		}
+	}
+}
+
+func TestSecureConnUnknownKey(t *testing.T) {
+	_, server, errs := securePair(t, []byte("other"), [][]byte{[]byte("team")})
+	if server != nil {
+		t.Fatal("server accepted an unknown key")
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("no error for an unknown key")
+	}
+}
+
+func TestSecureConnTampered(t *testing.T) {
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+
+	var (
+		key        = []byte("team")
+		salt       = make([]byte, saltSize)
+		client, _  = newSecureConn(c, key, true, salt, salt)
+		server, _  = newSecureConn(s, key, false, salt, salt)
+		readResult = make(chan error, 1)
+	)
+	go func() {
+		_, err := server.Read(make([]byte, 64))
+		readResult <- err
+	}()
+
+	// a frame sealed for the other direction does not decrypt
+	sealed := client.recv.Seal(nil, seqNonce(client.recv, 0), []byte("forged"), nil)
+	frame := append([]byte{0, 0, 0, byte(len(sealed))}, sealed...)
+	if _, err := c.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-readResult; err == nil {
+		t.Errorf("forged frame read")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package gitsync
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// setMulticastOptions is not supported on this platform. Only the system
+// defaults, which include loopback, can be used.
+func setMulticastOptions(conn *net.UDPConn, ipv6 bool, ifIndex int, ifAddr net.IP, ttl int, loopback bool) error {
+	if ifIndex != 0 || ifAddr != nil || ttl != 0 || !loopback {
+		return fmt.Errorf("multicast options are not supported on %s", runtime.GOOS)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package gitsync
+
+import (
+	"net"
+	"syscall"
+)
+
+// setMulticastOptions sets the outgoing interface, TTL or hop limit, and
+// loopback of multicast datagrams sent on conn. ifIndex and ifAddr select the
+// interface for IPv6 and IPv4 respectively and are ignored when zero.
+func setMulticastOptions(conn *net.UDPConn, ipv6 bool, ifIndex int, ifAddr net.IP, ttl int, loopback bool) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	loop := 0
+	if loopback {
+		loop = 1
+	}
+
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		s := int(fd)
+		if ipv6 {
+			if ifIndex != 0 {
+				if sockErr = syscall.SetsockoptInt(s, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, ifIndex); sockErr != nil {
+					return
+				}
+			}
+			if ttl != 0 {
+				if sockErr = syscall.SetsockoptInt(s, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, ttl); sockErr != nil {
+					return
+				}
+			}
+			sockErr = syscall.SetsockoptInt(s, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_LOOP, loop)
+			return
+		}
+
+		if ip4 := ifAddr.To4(); ip4 != nil {
+			var addr [4]byte
+			copy(addr[:], ip4)
+			if sockErr = syscall.SetsockoptInet4Addr(s, syscall.IPPROTO_IP, syscall.IP_MULTICAST_IF, addr); sockErr != nil {
+				return
+			}
+		}
+		if ttl != 0 {
+			if sockErr = syscall.SetsockoptByte(s, syscall.IPPROTO_IP, syscall.IP_MULTICAST_TTL, byte(ttl)); sockErr != nil {
+				return
+			}
+		}
+		sockErr = syscall.SetsockoptByte(s, syscall.IPPROTO_IP, syscall.IP_MULTICAST_LOOP, byte(loop))
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,65 @@
+package gitsync
+
+import (
+	"errors"
+	log "github.com/ngmoco/timber"
+	"net"
+)
+
+// Transport moves announcements between us and our peers. Announcements are
+// opaque to it: encoding, authentication and filtering happen above it, in
+// NetIO.
+type Transport interface {
+	// String names the transport in logs
+	String() string
+
+	// Send delivers an announcement to the peers reachable through the
+	// transport. encode produces the announcement with HostIp set to the
+	// address given, which should be one those peers can reach us at. It
+	// returns an error if the announcement could not be delivered at all, so
+	// that it can be sent again later.
+	Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error
+
+	// Receive passes the announcements received on to packets. It returns
+	// once the transport is closed.
+	Receive(l log.Logger, packets chan<- Packet)
+
+	// State reports whether the transport can currently reach peers
+	State() TransportState
+
+	// Close stops the transport
+	Close() error
+}
+
+// TransportState is the connectivity of a transport
+type TransportState struct {
+	Up     bool   // peers can be reached through the transport
+	Detail string // what the transport is connected to, or why it is not
+}
+
+// PeerAdder is implemented by transports that keep a list of peers, so peers
+// found by other means can be added to it
+type PeerAdder interface {
+	// AddPeer adds the peer at host:port
+	AddPeer(peer string)
+}
+
+// Packet is an announcement received by a Transport
+type Packet struct {
+	Data []byte
+	From net.Addr // sender, for logs
+	Zone string   // zone of the interface the announcement arrived on, for IPv6 link-local senders
+	TTL  int      // hops the announcement may still be forwarded by gossiping peers
+
+	// Accepted is called, if not nil, once the announcement has been decoded.
+	// trusted is set if it was authenticated with the shared secret or signed
+	// with an approved key, not one merely pinned on first use.
+	Accepted func(trusted bool)
+
+	via *channelTransport // transport the announcement arrived through
+}
+
+// isClosed reports whether err is the result of using a closed connection
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
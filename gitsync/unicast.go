@@ -0,0 +1,397 @@
+package gitsync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxSharedPeers  = 64               // most peers listed in one frame
+	maxUnicastPeers = 256              // most peers kept in the peer list
+	maxTCPFrameSize = 1 << 20          // largest frame accepted over TCP
+	learnedPeerTTL  = 24 * time.Hour   // how long a learned peer is kept without hearing from it
+	unicastTimeout  = 10 * time.Second // time allowed to deliver a frame over TCP
+)
+
+// UnicastOptions configure sending announcements directly to a list of peers,
+// for networks where multicast does not work
+type UnicastOptions struct {
+	Proto string   // "udp" or "tcp"
+	Port  int      // port to listen on
+	Peers []string // host:port of peers to seed the peer list with
+}
+
+// unicastFrame is what is sent to each peer. Along with the announcement, it
+// carries the address we listen on and the peers we know of, so that a team
+// only has to seed one address for everyone to find each other.
+type unicastFrame struct {
+	Port    int      // port the sender listens on
+	Peers   []string // host:port of peers known to the sender
+	Message []byte   // the announcement, as produced by encodeChanges
+}
+
+// unicast sends announcements to each known peer, by UDP or TCP, and receives
+// theirs
+type unicast struct {
+	opts     UnicastOptions
+	udpConn  *net.UDPConn  // listening socket, and the socket we send from, for UDP
+	listener net.Listener  // listening socket for TCP
+	done     chan struct{} // closed by Close
+
+	sync.Mutex                      // lock peers and closed
+	peers      map[string]time.Time // host:port -> last heard from, zero for seeds
+	closed     bool
+}
+
+// NewUnicastTransport starts listening for unicast announcements
+func NewUnicastTransport(opts UnicastOptions) (t Transport, err error) {
+	u := &unicast{
+		opts:  opts,
+		done:  make(chan struct{}),
+		peers: make(map[string]time.Time)}
+	for _, peer := range opts.Peers {
+		u.peers[peer] = time.Time{}
+	}
+
+	addr := fmt.Sprintf(":%d", opts.Port)
+	switch opts.Proto {
+	case "udp":
+		var laddr *net.UDPAddr
+		if laddr, err = net.ResolveUDPAddr("udp", addr); err == nil {
+			u.udpConn, err = net.ListenUDP("udp", laddr)
+		}
+	case "tcp":
+		u.listener, err = net.Listen("tcp", addr)
+	default:
+		err = fmt.Errorf("unknown unicast protocol %q", opts.Proto)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *unicast) String() string {
+	return fmt.Sprintf("%s unicast on %d", u.opts.Proto, u.opts.Port)
+}
+
+func (u *unicast) State() TransportState {
+	u.Lock()
+	defer u.Unlock()
+	return TransportState{Up: len(u.peers) > 0, Detail: fmt.Sprintf("%d peer(s) known", len(u.peers))}
+}
+
+func (u *unicast) Close() error {
+	u.Lock()
+	if !u.closed {
+		u.closed = true
+		close(u.done)
+	}
+	u.Unlock()
+	if u.udpConn != nil {
+		return u.udpConn.Close()
+	}
+	return u.listener.Close()
+}
+
+// AddPeer adds a peer found by other means, such as DNS-SD, to the peer list
+func (u *unicast) AddPeer(peer string) {
+	u.learn(peer, true)
+}
+
+// learn adds peer, a host:port, to the peer list. direct is set when the peer
+// contacted us itself, rather than being listed by another peer. Once the list
+// is full, a direct peer replaces the learned peer heard from least recently,
+// and listed peers are ignored.
+func (u *unicast) learn(peer string, direct bool) {
+	if isLocalAddr(peer, u.opts.Port) || (!direct && !isRelayableAddr(peer)) {
+		return
+	}
+
+	u.Lock()
+	defer u.Unlock()
+	if last, known := u.peers[peer]; known {
+		if direct && !last.IsZero() {
+			u.peers[peer] = time.Now()
+		}
+		return
+	}
+
+	if len(u.peers) >= maxUnicastPeers {
+		if !direct {
+			return
+		}
+		var (
+			oldest     string
+			oldestSeen time.Time
+		)
+		for p, last := range u.peers {
+			if !last.IsZero() && (oldest == "" || last.Before(oldestSeen)) {
+				oldest, oldestSeen = p, last
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		delete(u.peers, oldest)
+	}
+	u.peers[peer] = time.Now()
+}
+
+// isRelayableAddr reports whether peer, listed by another peer, may be sent
+// announcements. Only IP addresses are accepted, as a host name could resolve
+// anywhere, and of those only unicast addresses of other hosts.
+func isRelayableAddr(peer string) bool {
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() {
+		return false
+	}
+	return !isBroadcastAddr(ip)
+}
+
+// peerList returns the current peers, dropping learned ones we have not heard
+// from in a long time
+func (u *unicast) peerList() (peers []string) {
+	u.Lock()
+	defer u.Unlock()
+	for peer, last := range u.peers {
+		if !last.IsZero() && time.Since(last) > learnedPeerTTL {
+			delete(u.peers, peer)
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// isLocalAddr reports whether peer is ourselves listening on port
+func isLocalAddr(peer string, port int) bool {
+	host, peerPort, err := net.SplitHostPort(peer)
+	if err != nil || peerPort != strconv.Itoa(port) {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || interfaceOf(ip) != "unknown"
+}
+
+// localIPFor returns the address we would use to reach peer
+func localIPFor(peer string) (string, error) {
+	// connecting a UDP socket sends nothing, it only picks a route
+	conn, err := net.Dial("udp", peer)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// Send delivers an announcement to every peer, with HostIp set to the address
+// we reach the peer from. Over TCP, frames are delivered in the background, so
+// only failing to reach any peer at all is reported.
+func (u *unicast) Send(l log.Logger, encode func(hostIp string) ([]byte, error)) error {
+	var (
+		peers   = u.peerList()
+		shared  = peers
+		encoded = make(map[string][]byte) // hostIp -> announcement
+		lastErr = errors.New("no peers known")
+		sent    = 0
+	)
+	if len(shared) > maxSharedPeers {
+		shared = shared[:maxSharedPeers]
+	}
+
+	for _, peer := range peers {
+		hostIp, err := localIPFor(peer)
+		if err != nil {
+			l.Error("Cannot route to peer %s: %s", peer, err)
+			lastErr = err
+			continue
+		}
+		msg, found := encoded[hostIp]
+		if !found {
+			if msg, err = encode(hostIp); err != nil {
+				return err
+			}
+			encoded[hostIp] = msg
+		}
+
+		buf := &bytes.Buffer{}
+		if err := gob.NewEncoder(buf).Encode(unicastFrame{Port: u.opts.Port, Peers: shared, Message: msg}); err != nil {
+			return err
+		}
+
+		if u.udpConn != nil {
+			addr, err := net.ResolveUDPAddr("udp", peer)
+			if err == nil {
+				_, err = u.udpConn.WriteToUDP(buf.Bytes(), addr)
+			}
+			if err != nil {
+				l.Error("Cannot send to peer %s: %s", peer, err)
+				lastErr = err
+				continue
+			}
+			sent++
+			continue
+		}
+
+		go func(peer string, frame []byte) {
+			if err := sendTCPFrame(peer, frame); err != nil {
+				l.Error("Cannot send to peer %s: %s", peer, err)
+			}
+		}(peer, buf.Bytes())
+		sent++
+	}
+	if sent == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// sendTCPFrame connects to peer and sends it one length prefixed frame
+func sendTCPFrame(peer string, frame []byte) error {
+	conn, err := net.DialTimeout("tcp", peer, unicastTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(unicastTimeout))
+	return writeFrame(conn, frame)
+}
+
+// writeFrame writes frame to w, prefixed with its length
+func writeFrame(w io.Writer, frame []byte) error {
+	buf := make([]byte, 4+len(frame))
+	binary.BigEndian.PutUint32(buf, uint32(len(frame)))
+	copy(buf[4:], frame)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads one length prefixed frame from r
+func readFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxTCPFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes is too large", n)
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// readTCPFrames reads length prefixed frames from conn until it is closed
+func readTCPFrames(conn net.Conn, frames chan<- []byte) error {
+	for {
+		frame, err := readFrame(conn)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		frames <- frame
+	}
+}
+
+// Receive reads frames from peers and passes their announcements on to
+// packets. Once an announcement is accepted, its sender and the peers it lists
+// are added to the peer list. Reading or accepting is retried, with backoff,
+// when it fails.
+func (u *unicast) Receive(l log.Logger, packets chan<- Packet) {
+	var retry backoff
+	deliver := func(raw []byte, from net.IP, zone string) {
+		var frame unicastFrame
+		if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&frame); err != nil {
+			l.Warn("Dropping malformed frame from %s: %s", from, err)
+			return
+		}
+		sender := &net.UDPAddr{IP: from, Port: frame.Port, Zone: zone}
+		packets <- Packet{
+			Data: frame.Message,
+			From: sender,
+			Zone: zone,
+			Accepted: func(trusted bool) {
+				u.learn(sender.String(), true)
+				if !trusted {
+					return
+				}
+				for i, peer := range frame.Peers {
+					if i == maxSharedPeers {
+						break
+					}
+					u.learn(peer, false)
+				}
+			}}
+	}
+
+	if u.udpConn != nil {
+		for {
+			b := make([]byte, 65536)
+			n, from, err := u.udpConn.ReadFromUDP(b)
+			if isClosed(err) {
+				return
+			} else if err != nil {
+				l.Critical("Cannot read socket: %s", err)
+				if !retry.wait(u.done) {
+					return
+				}
+				continue
+			}
+			retry.reset()
+			deliver(b[:n], from.IP, from.Zone)
+		}
+	}
+
+	for {
+		conn, err := u.listener.Accept()
+		if isClosed(err) {
+			return
+		} else if err != nil {
+			l.Critical("Cannot accept connection: %s", err)
+			if !retry.wait(u.done) {
+				return
+			}
+			continue
+		}
+		retry.reset()
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(unicastTimeout))
+
+			frames := make(chan []byte)
+			go func() {
+				defer close(frames)
+				if err := readTCPFrames(conn, frames); err != nil {
+					l.Warn("Error reading from %s: %s", conn.RemoteAddr(), err)
+				}
+			}()
+
+			from := conn.RemoteAddr().(*net.TCPAddr)
+			for raw := range frames {
+				deliver(raw, from.IP, from.Zone)
+			}
+		}(conn)
+	}
+}
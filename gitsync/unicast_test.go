@@ -0,0 +1,58 @@
+package gitsync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsRelayableAddr(t *testing.T) {
+	for _, test := range []struct {
+		peer string
+		want bool
+	}{
+		{"192.0.2.1:9998", true},
+		{"[2001:db8::1]:9998", true},
+		{"example.com:9998", false},
+		{"127.0.0.1:9998", false},
+		{"[::1]:9998", false},
+		{"0.0.0.0:9998", false},
+		{"224.0.0.1:9998", false},
+		{"[ff02::1]:9998", false},
+		{"255.255.255.255:9998", false},
+		{"192.0.2.1", false},
+	} {
+		if got := isRelayableAddr(test.peer); got != test.want {
+			t.Errorf("isRelayableAddr(%s) = %t, want %t", test.peer, got, test.want)
+		}
+	}
+}
+
+func TestUnicastLearnCap(t *testing.T) {
+	u := &unicast{
+		opts:  UnicastOptions{Port: 9998},
+		peers: map[string]time.Time{"192.0.2.1:9998": {}}}
+	for i := 0; len(u.peers) < maxUnicastPeers; i++ {
+		u.learn(fmt.Sprintf("198.51.%d.%d:9998", i/256, i%256), false)
+	}
+	oldest := "198.51.0.0:9998"
+	u.peers[oldest] = time.Now().Add(-time.Hour)
+
+	// listed peers are ignored once the list is full
+	u.learn("203.0.113.1:9998", false)
+	if _, found := u.peers["203.0.113.1:9998"]; found || len(u.peers) != maxUnicastPeers {
+		t.Errorf("listed peer added to a full list of %d peers", len(u.peers))
+	}
+
+	// a direct peer replaces the one heard from least recently, never a seed
+	u.learn("203.0.113.2:9998", true)
+	if _, found := u.peers["203.0.113.2:9998"]; !found || len(u.peers) != maxUnicastPeers {
+		t.Errorf("direct peer not added to a full list of %d peers", len(u.peers))
+	}
+	if _, found := u.peers[oldest]; found {
+		t.Errorf("oldest peer %s kept", oldest)
+	}
+	if _, found := u.peers["192.0.2.1:9998"]; !found {
+		t.Errorf("seed dropped")
+	}
+}
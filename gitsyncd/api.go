@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultCommits is how many commits /api/branches/.../commits returns
+// without a limit
+const defaultCommits = 50
+
+// peerMirror finds the mirror branch of the peer's branch, the peer being
+// given by its user name or as <user>-<peer id> when several share the name
+func peerMirror(mirrors []gitsync.Mirror, user, branch string) (gitsync.Mirror, error) {
+	var found []gitsync.Mirror
+	for _, m := range mirrors {
+		if c := m.Change; c != nil && c.RefName == branch && (c.User == user || c.User+"-"+gitsync.ShortPeerID(c.PeerID) == user) {
+			found = append(found, m)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return gitsync.Mirror{}, fmt.Errorf("%s has not announced %s", user, branch)
+	case 1:
+		return found[0], nil
+	}
+	var names []string
+	for _, m := range found {
+		names = append(names, m.Change.User+"-"+gitsync.ShortPeerID(m.Change.PeerID))
+	}
+	return gitsync.Mirror{}, fmt.Errorf("several peers are called %s, use one of %s", user, strings.Join(names, ", "))
+}
+
+// apiHandler serves the web server's JSON API, for scripts and dashboards that
+// do not want to keep the /events websocket open:
+//
+//	GET /api/peers                            the peers heard from, as []gitsync.Peer
+//	GET /api/repos                            the repos watched, as []gitsync.RepoInfo
+//	GET /api/branches                         the mirror branches as []gitsync.Mirror, those of ?user= only if given
+//	GET /api/events                           recent events as []gitsync.Event, those after ?since=<seq> only if given
+//	GET /api/branches/<user>/<branch>/commits the last ?limit=n (50) commits of a peer's branch, as []gitsync.Commit
+//
+// Failed calls return a gitsync.ControlError.
+func apiHandler(c *controller) http.Handler {
+	mux := http.NewServeMux()
+
+	handle(mux, "GET", "/api/peers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Peers())
+	})
+
+	handle(mux, "GET", "/api/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Repos())
+	})
+
+	handle(mux, "GET", "/api/branches", func(w http.ResponseWriter, r *http.Request) {
+		mirrors, err := c.Mirrors()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if user := r.URL.Query().Get("user"); user != "" {
+			var mine []gitsync.Mirror
+			for _, m := range mirrors {
+				if m.Change != nil && m.Change.User == user {
+					mine = append(mine, m)
+				}
+			}
+			mirrors = mine
+		}
+		if mirrors == nil {
+			mirrors = []gitsync.Mirror{}
+		}
+		writeJSON(w, http.StatusOK, mirrors)
+	})
+
+	handle(mux, "GET", "/api/events", func(w http.ResponseWriter, r *http.Request) {
+		var since uint64
+		if s := r.URL.Query().Get("since"); s != "" {
+			var err error
+			if since, err = strconv.ParseUint(s, 10, 64); err != nil {
+				writeError(w, http.StatusBadRequest, "bad since "+s)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, c.EventsSince(since))
+	})
+
+	// the branch may hold slashes, so the path is split by hand
+	handle(mux, "GET", "/api/branches/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/branches/")
+		slash := strings.Index(rest, "/")
+		if !strings.HasSuffix(rest, "/commits") || slash <= 0 || slash >= len(rest)-len("/commits") {
+			writeError(w, http.StatusNotFound, "no such endpoint "+r.URL.Path)
+			return
+		}
+		user, branch := rest[:slash], strings.TrimSuffix(rest[slash+1:], "/commits")
+
+		n := defaultCommits
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			var err error
+			if n, err = strconv.Atoi(limit); err != nil || n <= 0 {
+				writeError(w, http.StatusBadRequest, "bad limit "+limit)
+				return
+			}
+		}
+
+		mirrors, err := c.Mirrors()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		m, err := peerMirror(mirrors, user, branch)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if m.Current == "" {
+			writeError(w, http.StatusNotFound, m.Branch+" has not been fetched")
+			return
+		}
+		commits, err := c.Commits(m.Branch, n)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, commits)
+	})
+
+	return mux
+}
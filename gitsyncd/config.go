@@ -0,0 +1,535 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"github.com/raybejjani/gitsync/gitsync"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemConfig is the config file shared by the users of a machine. The
+// user's own is gitsync/config in their config directory, and each repo may
+// have one in .git/gitsync/config.
+const systemConfig = "/etc/gitsync/config"
+
+// projectConfig is the config file committed at the top of a repo, holding
+// the settings its whole team shares. Missing from the work tree, it is read
+// from the tip of the default branch.
+const projectConfig = ".gitsync"
+
+// projectKeys are the settings a project config may hold. Others, such as the
+// commands of notify rules, are left to each user.
+var projectKeys = map[string]bool{
+	"channel":     true,
+	"share":       true,
+	"repoid":      true,
+	"ignorepaths": true,
+}
+
+// reloadableFlags are the settings that take effect when the config files are
+// reloaded. Changing the others needs a restart.
+var reloadableFlags = map[string]bool{
+	"loglevel":    true,
+	"secretfile":  true,
+	"peers":       true,
+	"peersfile":   true,
+	"ignorepaths": true,
+}
+
+// fetchRules choose which of the peers' branches are fetched. Empty lists
+// match any branch.
+type fetchRules struct {
+	Users      []string // users whose branches are fetched
+	Branches   []string // path.Match patterns of the branches fetched, e.g. feature/*
+	Ignore     []string // patterns of branches never fetched
+	CheckedOut bool     // only fetch branches their owner has checked out
+}
+
+// matchAny reports whether any of patterns matches name
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the branch change announces is to be fetched
+func (r fetchRules) match(change gitsync.GitChange) bool {
+	if len(r.Users) > 0 && !matchAny(r.Users, change.User) {
+		return false
+	}
+	if len(r.Branches) > 0 && !matchAny(r.Branches, change.RefName) {
+		return false
+	}
+	if matchAny(r.Ignore, change.RefName) {
+		return false
+	}
+	return !r.CheckedOut || change.CheckedOut
+}
+
+// notifyRule runs Command, with sh, for the changes Filter matches
+type notifyRule struct {
+	Filter  gitsync.ChangeFilter
+	Command string
+}
+
+// run runs the rule's command in the background, describing change and the
+// mirror branch it is fetched into in GITSYNC_* environment variables
+func (r notifyRule) run(change gitsync.GitChange, mirror string) {
+	cmd := exec.Command("sh", "-c", r.Command)
+	cmd.Env = append(os.Environ(),
+		"GITSYNC_USER="+change.User,
+		"GITSYNC_PEER="+change.PeerID,
+		"GITSYNC_HOST="+change.HostIp,
+		"GITSYNC_REPO="+change.RepoName,
+		"GITSYNC_BRANCH="+change.RefName,
+		"GITSYNC_PREV="+change.Prev,
+		"GITSYNC_CURRENT="+change.Current,
+		"GITSYNC_CHECKEDOUT="+strconv.FormatBool(change.CheckedOut),
+		"GITSYNC_MIRROR="+mirror)
+	go func() {
+		if out, err := cmd.CombinedOutput(); err != nil {
+			log.Warn("Notification command %q failed: %s: %s", r.Command, err, strings.TrimSpace(string(out)))
+		}
+	}()
+}
+
+// config is what the config files set
+type config struct {
+	Repo   string            // repo to watch if none is given on the command line
+	Flags  map[string]string // flag name -> value, from the top level keys
+	Fetch  fetchRules
+	Notify []notifyRule
+}
+
+// configFiles returns the config files that apply to the repo at dir, least
+// specific first: the system's, the project's, the user's and the repo's own.
+// dir may be empty, before the repo is known.
+func configFiles(dir string) []string {
+	files := []string{systemConfig}
+	if dir != "" {
+		files = append(files, filepath.Join(dir, projectConfig))
+	}
+	if userDir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(userDir, "gitsync", "config"))
+	}
+	if dir != "" {
+		files = append(files, filepath.Join(dir, ".git", "gitsync", "config"))
+	}
+	return files
+}
+
+// defaultBranch returns the default branch of the repo at dir: the one its
+// origin's HEAD points to, or else main or master
+func defaultBranch(dir string) (string, error) {
+	cmd := exec.Command("git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	cmd.Dir = dir
+	if out, err := cmd.Output(); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+	for _, branch := range []string{"main", "master"} {
+		cmd = exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+		cmd.Dir = dir
+		if cmd.Run() == nil {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("no default branch")
+}
+
+// readConfigFile reads file. A project config missing from the work tree is
+// read from the tip of the default branch, if it is there.
+func readConfigFile(file string) ([]byte, error) {
+	data, err := ioutil.ReadFile(file)
+	if !os.IsNotExist(err) || filepath.Base(file) != projectConfig {
+		return data, err
+	}
+
+	dir := filepath.Dir(file)
+	branch, branchErr := defaultBranch(dir)
+	if branchErr != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "show", branch+":"+projectConfig)
+	cmd.Dir = dir
+	if data, branchErr = cmd.Output(); branchErr != nil {
+		return nil, err
+	}
+	log.Debug("Reading %s from %s", projectConfig, branch)
+	return data, nil
+}
+
+// flagValue formats a config value as a flag's command line value, joining
+// arrays with commas
+func flagValue(v interface{}) string {
+	switch v := v.(type) {
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, flagValue(item))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// stringList reads a value given as a string or as an array of strings
+func stringList(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected strings, got %v", item)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expected a string or an array of strings, got %v", v)
+}
+
+// configString and configBool read values of the given types
+func configString(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("expected a string, got %v", v)
+}
+
+func configBool(v interface{}) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("expected true or false, got %v", v)
+}
+
+// merge adds the tables of a config file to cfg, overriding the values it
+// already has. Notify rules are added to those already there. A project config
+// may only set projectKeys.
+func (cfg *config) merge(tables []*tomlTable, project bool) error {
+	for _, table := range tables {
+		if project {
+			if table.Name != "" {
+				return fmt.Errorf("line %d: [%s] cannot be set in %s", table.Line, table.Name, projectConfig)
+			}
+			for key := range table.Values {
+				if !projectKeys[key] {
+					return fmt.Errorf("%s cannot be set in %s", key, projectConfig)
+				}
+			}
+		}
+		if table.Name != "" && table.Name != "fetch" && table.Name != "notify" {
+			return fmt.Errorf("line %d: unknown table %s", table.Line, table.Name)
+		}
+		for key, v := range table.Values {
+			var err error
+			switch {
+			case table.Name == "" && key == "repo":
+				cfg.Repo, err = configString(v)
+			case table.Name == "" && key != "config" && flag.Lookup(key) != nil:
+				cfg.Flags[key] = flagValue(v)
+			case table.Name == "fetch" && key == "users":
+				cfg.Fetch.Users, err = stringList(v)
+			case table.Name == "fetch" && key == "branches":
+				cfg.Fetch.Branches, err = stringList(v)
+			case table.Name == "fetch" && key == "ignore":
+				cfg.Fetch.Ignore, err = stringList(v)
+			case table.Name == "fetch" && key == "checkedout":
+				cfg.Fetch.CheckedOut, err = configBool(v)
+			case table.Name == "notify":
+				// read below, once all of the rule is known
+			default:
+				err = fmt.Errorf("unknown setting")
+			}
+			if err != nil {
+				if table.Name != "" {
+					key = table.Name + "." + key
+				}
+				return fmt.Errorf("%s: %s", key, err)
+			}
+		}
+
+		if table.Name == "notify" {
+			rule, err := readNotifyRule(table)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", table.Line, err)
+			}
+			cfg.Notify = append(cfg.Notify, rule)
+		}
+	}
+	return nil
+}
+
+// readNotifyRule reads a [[notify]] table
+func readNotifyRule(table *tomlTable) (rule notifyRule, err error) {
+	if !table.Array {
+		return rule, fmt.Errorf("notify rules are given as [[notify]]")
+	}
+	for key, v := range table.Values {
+		switch key {
+		case "user":
+			rule.Filter.User, err = configString(v)
+		case "peer":
+			rule.Filter.PeerID, err = configString(v)
+		case "repo":
+			rule.Filter.RepoName, err = configString(v)
+		case "branch":
+			rule.Filter.RefName, err = configString(v)
+		case "checkedout":
+			rule.Filter.CheckedOut, err = configBool(v)
+		case "command":
+			rule.Command, err = configString(v)
+		default:
+			err = fmt.Errorf("unknown setting")
+		}
+		if err != nil {
+			return rule, fmt.Errorf("notify.%s: %s", key, err)
+		}
+	}
+	if rule.Command == "" {
+		return rule, fmt.Errorf("notify rule without a command")
+	}
+	return rule, nil
+}
+
+// loadConfig reads files in turn, later ones overriding earlier ones. Missing
+// files are skipped.
+func loadConfig(files []string) (*config, error) {
+	cfg := &config{Flags: make(map[string]string)}
+	for _, file := range files {
+		data, err := readConfigFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		tables, err := parseTOML(string(data))
+		if err == nil {
+			err = cfg.merge(tables, filepath.Base(file) == projectConfig)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		log.Debug("Read config file %s", file)
+	}
+	return cfg, nil
+}
+
+// settings holds the configuration in effect, which is replaced when the
+// config files are reloaded
+type settings struct {
+	file    string          // config file given with -config, read instead of searching
+	dir     string          // repo the settings are for, empty until it is known
+	ignore  *string         // the -ignorepaths flag, which reloads set
+	files   []string        // config files read
+	cmdline map[string]bool // flags given on the command line, which the files do not override
+
+	sync.RWMutex
+	cfg *config
+}
+
+// newSettings returns the settings of the flags given on the command line,
+// which must have been parsed, and of file if not empty. ignore is the
+// -ignorepaths flag.
+func newSettings(file string, ignore *string) *settings {
+	s := &settings{
+		file:    file,
+		ignore:  ignore,
+		cmdline: make(map[string]bool),
+		cfg:     &config{Flags: make(map[string]string)}}
+	flag.Visit(func(f *flag.Flag) {
+		s.cmdline[f.Name] = true
+	})
+	return s
+}
+
+// load reads the config files for the repo at dir, which may be empty, and sets
+// the flags not given on the command line from them
+func (s *settings) load(dir string) error {
+	files := configFiles(dir)
+	if s.file != "" {
+		files = []string{s.file}
+		if dir != "" {
+			files = []string{filepath.Join(dir, projectConfig), s.file}
+		}
+	}
+	cfg, err := loadConfig(files)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range cfg.Flags {
+		if s.cmdline[name] {
+			continue
+		}
+		if err = flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.dir, s.files, s.cfg = dir, files, cfg
+	return nil
+}
+
+// reload reads the config files again. The reloadable flags and the fetch and
+// notify rules are updated. The other settings that changed, which only take
+// effect on restart, are logged and returned.
+func (s *settings) reload() (restart []string, err error) {
+	cfg, err := loadConfig(s.files)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	changed := make(map[string]bool)
+	for name, value := range cfg.Flags {
+		if old, ok := s.cfg.Flags[name]; !ok || old != value {
+			changed[name] = true
+		}
+	}
+	for name := range s.cfg.Flags {
+		if _, ok := cfg.Flags[name]; !ok {
+			changed[name] = true
+		}
+	}
+
+	for name := range changed {
+		switch {
+		case s.cmdline[name]:
+		case !reloadableFlags[name]:
+			restart = append(restart, name)
+		default:
+			value, ok := cfg.Flags[name]
+			if !ok {
+				value = flag.Lookup(name).DefValue
+			}
+			if err = flag.Set(name, value); err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err)
+			}
+		}
+	}
+	if cfg.Repo != s.cfg.Repo {
+		restart = append(restart, "repo")
+	}
+	s.cfg = cfg
+
+	sort.Strings(restart)
+	if len(restart) > 0 {
+		log.Warn("%s changed in the config files, restart gitsyncd for the change to take effect", strings.Join(restart, ", "))
+	}
+	return restart, nil
+}
+
+// repo returns the repo to watch given in the config files, if any
+func (s *settings) repo() string {
+	s.RLock()
+	defer s.RUnlock()
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(s.cfg.Repo, "~/") {
+		return filepath.Join(home, s.cfg.Repo[2:])
+	}
+	return s.cfg.Repo
+}
+
+// fetches reports whether the branch change announces is to be fetched
+func (s *settings) fetches(change gitsync.GitChange) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.cfg.Fetch.match(change)
+}
+
+// notify runs the notify rules matching change, whose branch is fetched into
+// mirror, unless the change only touches the paths -ignorepaths matches
+func (s *settings) notify(change gitsync.GitChange, mirror string) {
+	s.RLock()
+	defer s.RUnlock()
+	var rules []notifyRule
+	for _, rule := range s.cfg.Notify {
+		if rule.Filter.Match(change) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	var ignore []string
+	for _, pattern := range strings.Split(*s.ignore, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			ignore = append(ignore, pattern)
+		}
+	}
+	if len(ignore) > 0 && onlyTouches(s.dir, change, ignore) {
+		log.Debug("Not notifying of %s, which only touches ignored paths", mirror)
+		return
+	}
+	for _, rule := range rules {
+		rule.run(change, mirror)
+	}
+}
+
+// ignoredPath reports whether p, one of its directories or one of their base
+// names matches any of patterns
+func ignoredPath(patterns []string, p string) bool {
+	for ; p != "." && p != "/"; p = path.Dir(p) {
+		if matchAny(patterns, p) || matchAny(patterns, path.Base(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommitHash reports whether s is a full SHA-1 or SHA-256 commit hash
+func isCommitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyTouches reports whether all the paths change touches in the repo at dir
+// are matched by patterns. It is false when that cannot be told, such as for a
+// new branch, commits not fetched or commits that are not hashes, the change
+// coming from the network.
+func onlyTouches(dir string, change gitsync.GitChange, patterns []string) bool {
+	if !isCommitHash(change.Prev) || !isCommitHash(change.Current) {
+		return false
+	}
+	cmd := exec.Command("git", "diff", "--name-only", "--end-of-options", change.Prev, change.Current)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+
+	paths := strings.Split(strings.TrimSpace(string(out)), "\n")
+	for _, p := range paths {
+		if !ignoredPath(patterns, p) {
+			return false
+		}
+	}
+	return paths[0] != ""
+}
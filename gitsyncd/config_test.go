@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"github.com/raybejjani/gitsync/gitsync"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gitIn runs git in dir, returning its trimmed output
+func gitIn(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestOnlyTouches(t *testing.T) {
+	dir := newTestRepo(t)
+	root := gitIn(t, dir, "rev-parse", "HEAD")
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, dir, "add", "-A")
+	gitIn(t, dir, "commit", "-q", "-m", "docs")
+	docs := gitIn(t, dir, "rev-parse", "HEAD")
+
+	output := filepath.Join(t.TempDir(), "written")
+	for _, c := range []struct {
+		prev, current string
+		want          bool
+	}{
+		{root, docs, true},
+		{"", docs, false},
+		{root[:7], docs, false},
+		{"--output=" + output, docs, false},
+		{root, "--output=" + output, false},
+	} {
+		change := gitsync.GitChange{Prev: c.prev, Current: c.current}
+		if got := onlyTouches(dir, change, []string{"docs"}); got != c.want {
+			t.Errorf("onlyTouches(%s..%s) = %v, want %v", c.prev, c.current, got, c.want)
+		}
+	}
+	if _, err := os.Stat(output); err == nil {
+		t.Errorf("git wrote %s", output)
+	}
+	if onlyTouches(dir, gitsync.GitChange{Prev: root, Current: docs}, []string{"src"}) {
+		t.Errorf("onlyTouches is true for paths not ignored")
+	}
+}
+
+var defineFlags sync.Once
+
+// configFlags defines the flags the config tests set, which main defines when
+// gitsyncd runs, and resets them to their defaults
+func configFlags(t *testing.T) {
+	defineFlags.Do(func() {
+		flag.String("channel", "", "")
+		flag.String("share", "", "")
+		flag.String("ignorepaths", "", "")
+		flag.String("loglevel", "info", "")
+		flag.Bool("gossip", false, "")
+	})
+	for _, name := range []string{"channel", "share", "ignorepaths", "loglevel", "gossip"} {
+		f := flag.Lookup(name)
+		if err := f.Value.Set(f.DefValue); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeConfig writes a config file named name in dir, returning its path
+func writeConfig(t *testing.T, dir, name, data string) string {
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	configFlags(t)
+	dir := t.TempDir()
+	files := []string{
+		writeConfig(t, dir, "system", `
+channel = "system"
+loglevel = "debug"
+[[notify]]
+command = "system-notify"
+`),
+		writeConfig(t, dir, projectConfig, `
+channel = "project"
+share = ["main", "release/*"]
+`),
+		writeConfig(t, dir, "user", `
+repo = "~/src/repo"
+loglevel = "warning"
+gossip = true
+[fetch]
+users = "alice"
+branches = ["main", "topic/*"]
+checkedout = true
+[[notify]]
+user = "bob"
+command = "user-notify"
+`),
+		filepath.Join(dir, "missing"),
+	}
+
+	cfg, err := loadConfig(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config{
+		Repo: "~/src/repo",
+		Flags: map[string]string{
+			"channel":  "project",
+			"share":    "main,release/*",
+			"loglevel": "warning",
+			"gossip":   "true",
+		},
+		Fetch: fetchRules{Users: []string{"alice"}, Branches: []string{"main", "topic/*"}, CheckedOut: true},
+		Notify: []notifyRule{
+			{Command: "system-notify"},
+			{Filter: gitsync.ChangeFilter{User: "bob"}, Command: "user-notify"},
+		},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("loadConfig = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	configFlags(t)
+	for _, test := range []struct {
+		name, file, data string
+	}{
+		{"syntax", "config", "channel ="},
+		{"unknown setting", "config", "nosuchflag = 1"},
+		{"unknown table", "config", "[nosuchtable]"},
+		{"unknown fetch setting", "config", "[fetch]\nnosuch = 1"},
+		{"wrong type", "config", "[fetch]\ncheckedout = \"yes\""},
+		{"wrong list type", "config", "[fetch]\nusers = [1, 2]"},
+		{"notify without a command", "config", "[[notify]]\nuser = \"bob\""},
+		{"notify as a table", "config", "[notify]\ncommand = \"true\""},
+		{"project table", projectConfig, "[fetch]\nusers = \"alice\""},
+		{"project key", projectConfig, "loglevel = \"debug\""},
+	} {
+		file := writeConfig(t, t.TempDir(), test.file, test.data)
+		if cfg, err := loadConfig([]string{file}); err == nil {
+			t.Errorf("%s: loadConfig = %+v", test.name, cfg)
+		}
+	}
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	configFlags(t)
+	file := writeConfig(t, t.TempDir(), "config", `
+channel = "file"
+loglevel = "debug"
+share = "main"
+`)
+
+	// flags given on the command line win over the files
+	flag.Set("channel", "cmdline")
+	s := &settings{
+		file:    file,
+		cmdline: map[string]bool{"channel": true},
+		cfg:     &config{Flags: make(map[string]string)}}
+	if err := s.load(""); err != nil {
+		t.Fatal(err)
+	}
+	checkFlags(t, map[string]string{"channel": "cmdline", "loglevel": "debug", "share": "main"})
+
+	// reloading only changes the reloadable flags, others need a restart, and
+	// a setting removed from the files is back to its default
+	writeConfig(t, filepath.Dir(file), "config", `
+channel = "changed"
+share = "changed"
+gossip = true
+`)
+	restart, err := s.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFlags(t, map[string]string{"channel": "cmdline", "loglevel": "info", "share": "main", "gossip": "false"})
+	if got := strings.Join(restart, " "); got != "gossip share" {
+		t.Errorf("reload reported %q needing a restart, want gossip and share", got)
+	}
+}
+
+func checkFlags(t *testing.T, want map[string]string) {
+	t.Helper()
+	for name, value := range want {
+		if got := flag.Lookup(name).Value.String(); got != value {
+			t.Errorf("-%s = %q, want %q", name, got, value)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"github.com/raybejjani/gitsync/gitsync"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxEvents is how many recent events are kept by default, see -history
+const maxEvents = 200
+
+// controlActions are what the control API can have the daemon do
+type controlActions struct {
+	Fetch    func(change gitsync.GitChange) error // fetch a peer's branch
+	Cleanup  func()                               // delete the mirror branches
+	SetLevel func(level string) error             // change the log level
+	Reload   func() ([]string, error)             // re-read the configuration, returning the changed settings needing a restart
+}
+
+// controller keeps track of what the daemon sees and does, and serves it,
+// along with calls to act on it, over the control API
+type controller struct {
+	self       gitsync.Status      // who we are, without the counts and states
+	transports []gitsync.Transport // reported on by Status
+	actions    controlActions
+
+	sync.Mutex                              // lock the fields below
+	peers      map[string]*gitsync.Peer     // peer ID, or user for peers too old to send one -> peer
+	changes    map[string]gitsync.GitChange // mirror branch -> last announcement
+	fetchErrs  map[string]string            // mirror branch -> why it was last not fetched
+	history    *history                     // recent events, with its own lock
+}
+
+// newController returns a controller for the daemon described by self, which
+// must have its Path set, recording events in events
+func newController(self gitsync.Status, transports []gitsync.Transport, events *history, actions controlActions) *controller {
+	return &controller{
+		self:       self,
+		transports: transports,
+		actions:    actions,
+		history:    events,
+		peers:      make(map[string]*gitsync.Peer),
+		changes:    make(map[string]gitsync.GitChange),
+		fetchErrs:  make(map[string]string)}
+}
+
+// record adds an event to the history. It must be called with the lock held,
+// so that events are numbered in the order they happened.
+func (c *controller) record(kind, message string, change *gitsync.GitChange) {
+	c.history.add(gitsync.Event{Time: time.Now(), Kind: kind, Message: message, Change: change})
+}
+
+// saw records an announcement received from a peer
+func (c *controller) saw(change gitsync.GitChange) {
+	c.Lock()
+	defer c.Unlock()
+
+	id := change.PeerID
+	if id == "" {
+		id = change.User
+	}
+	peer := &gitsync.Peer{
+		PeerID:      change.PeerID,
+		User:        change.User,
+		HostIp:      change.HostIp,
+		LastSeen:    time.Now(),
+		KeyMismatch: change.KeyMismatch}
+	// branches are announced as no longer checked out when the peer switches
+	// away from them, and announcements of other branches say nothing of it
+	if old, found := c.peers[id]; found && old.CheckedOut != change.RefName {
+		peer.CheckedOut = old.CheckedOut
+	}
+	if change.CheckedOut {
+		peer.CheckedOut = change.RefName
+	}
+	c.peers[id] = peer
+	c.changes[c.self.Naming.Branch(change)] = change
+	c.record(gitsync.EventChange, fmt.Sprintf("%s moved %s to %s", change.User, change.RefName, change.Current), &change)
+}
+
+// fetch fetches the branch change announces, recording the outcome
+func (c *controller) fetch(change gitsync.GitChange) error {
+	err := c.actions.Fetch(change)
+
+	c.Lock()
+	defer c.Unlock()
+	branch := c.self.Naming.Branch(change)
+	if err != nil {
+		c.fetchErrs[branch] = err.Error()
+		c.record(gitsync.EventFetchError, fmt.Sprintf("cannot fetch %s: %s", branch, err), &change)
+	} else {
+		delete(c.fetchErrs, branch)
+		c.record(gitsync.EventFetch, "fetched "+branch, &change)
+	}
+	return err
+}
+
+// cleanup deletes the mirror branches
+func (c *controller) cleanup() {
+	c.actions.Cleanup()
+
+	c.Lock()
+	defer c.Unlock()
+	c.fetchErrs = make(map[string]string)
+	c.record(gitsync.EventCleanup, "deleted the gitsync branches", nil)
+}
+
+// reload re-reads the configuration, recording that it did and which of the
+// changed settings need a restart
+func (c *controller) reload() error {
+	restart, err := c.actions.Reload()
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	message := "configuration reloaded"
+	if len(restart) > 0 {
+		message += ", restart for changes to " + strings.Join(restart, ", ") + " to take effect"
+	}
+	c.record(gitsync.EventReload, message, nil)
+	return nil
+}
+
+// Status describes the daemon and the state of its transports
+func (c *controller) Status() gitsync.Status {
+	status := c.self
+	status.Transports = []gitsync.TransportStatus{}
+	for _, t := range c.transports {
+		status.Transports = append(status.Transports, gitsync.TransportStatus{Name: t.String(), TransportState: t.State()})
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	status.Peers = len(c.peers)
+	return status
+}
+
+// Peers returns the peers heard from, by user
+func (c *controller) Peers() []gitsync.Peer {
+	c.Lock()
+	defer c.Unlock()
+	peers := make([]gitsync.Peer, 0, len(c.peers))
+	for _, p := range c.peers {
+		peers = append(peers, *p)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].User != peers[j].User {
+			return peers[i].User < peers[j].User
+		}
+		return peers[i].PeerID < peers[j].PeerID
+	})
+	return peers
+}
+
+// Mirrors returns the mirror branches in the repo, along with those announced
+// but not fetched, by name
+func (c *controller) Mirrors() ([]gitsync.Mirror, error) {
+	local, err := mirrorBranches(c.self.Path, c.self.Naming)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	byName := make(map[string]*gitsync.Mirror)
+	for branch, tip := range local {
+		tip := tip
+		byName[branch] = &gitsync.Mirror{Branch: branch, Current: tip.Hash, Tip: &tip}
+	}
+	for branch, change := range c.changes {
+		m, found := byName[branch]
+		if !found {
+			m = &gitsync.Mirror{Branch: branch}
+			byName[branch] = m
+		}
+		change := change
+		m.Change = &change
+		m.FetchError = c.fetchErrs[branch]
+	}
+
+	mirrors := make([]gitsync.Mirror, 0, len(byName))
+	for _, m := range byName {
+		mirrors = append(mirrors, *m)
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].Branch < mirrors[j].Branch })
+	return mirrors, nil
+}
+
+// Events returns the last n events, or all kept if n is 0, oldest first
+func (c *controller) Events(n int) []gitsync.Event {
+	return c.history.last(n)
+}
+
+// EventsSince returns the events kept that came after the one numbered seq,
+// oldest first
+func (c *controller) EventsSince(seq uint64) []gitsync.Event {
+	events, _ := c.history.since(seq)
+	return events
+}
+
+// Repos returns the repos the daemon watches
+func (c *controller) Repos() []gitsync.RepoInfo {
+	return []gitsync.RepoInfo{{Name: filepath.Base(c.self.Path), Path: c.self.Path, ID: c.self.RepoID}}
+}
+
+// Commits returns the last n commits of the mirror branch, newest first
+func (c *controller) Commits(branch string, n int) ([]gitsync.Commit, error) {
+	cmd := exec.Command("git", "log", "-n", strconv.Itoa(n), "--format=%H%x00%an%x00%at%x00%s", "refs/heads/"+branch, "--")
+	cmd.Dir = c.self.Path
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the commits of %s: %s", branch, err)
+	}
+
+	commits := []gitsync.Commit{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		at, _ := strconv.ParseInt(fields[2], 10, 64)
+		commits = append(commits, gitsync.Commit{Hash: fields[0], Author: fields[1], Time: time.Unix(at, 0).UTC(), Subject: fields[3]})
+	}
+	return commits, nil
+}
+
+// mirrorBranches lists the mirror branches in the repo at dir, with the commit
+// each is at
+func mirrorBranches(dir string, naming gitsync.MirrorNaming) (map[string]gitsync.Commit, error) {
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)%00%(objectname)%00%(authorname)%00%(authordate:unix)%00%(subject)", "refs/heads")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list branches: %s", err)
+	}
+
+	branches := make(map[string]gitsync.Commit)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\x00", 5)
+		if len(fields) != 5 || !naming.IsMirror(fields[0]) {
+			continue
+		}
+		at, _ := strconv.ParseInt(fields[3], 10, 64)
+		branches[fields[0]] = gitsync.Commit{Hash: fields[1], Author: fields[2], Time: time.Unix(at, 0).UTC(), Subject: fields[4]}
+	}
+	return branches, nil
+}
+
+// writeJSON sends v as the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn("Cannot write control response: %s", err)
+	}
+}
+
+// writeError sends a failed call's response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, gitsync.ControlError{Error: message})
+}
+
+// handle registers h for path on mux, refusing other methods than method
+func handle(mux *http.ServeMux, method, path string, h http.HandlerFunc) {
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, r.Method+" is not allowed, use "+method)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// readJSON decodes the request body into v. An empty body leaves v untouched.
+func readJSON(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("bad request body: %s", err)
+	}
+	return nil
+}
+
+// handler serves the control API:
+//
+//	GET  /status    the daemon, as a gitsync.Status
+//	GET  /peers     the peers heard from, as []gitsync.Peer
+//	GET  /branches  the mirror branches, as []gitsync.Mirror
+//	GET  /events    recent events as []gitsync.Event, the last ?limit=n or those after ?since=<seq> only if given
+//	POST /fetch     fetch the gitsync.FetchRequest's branch, or all of them, again
+//	POST /cleanup   delete the mirror branches
+//	POST /loglevel  change the log level to the gitsync.LogLevelRequest's
+//	POST /reload    re-read the config, secret and peers files
+//
+// Failed calls return a gitsync.ControlError.
+func (c *controller) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	handle(mux, "GET", "/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+
+	handle(mux, "GET", "/peers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Peers())
+	})
+
+	handle(mux, "GET", "/branches", func(w http.ResponseWriter, r *http.Request) {
+		mirrors, err := c.Mirrors()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, mirrors)
+	})
+
+	handle(mux, "GET", "/events", func(w http.ResponseWriter, r *http.Request) {
+		if since := r.URL.Query().Get("since"); since != "" {
+			seq, err := strconv.ParseUint(since, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "bad since "+since)
+				return
+			}
+			writeJSON(w, http.StatusOK, c.EventsSince(seq))
+			return
+		}
+
+		n := 0
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			var err error
+			if n, err = strconv.Atoi(limit); err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, "bad limit "+limit)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, c.Events(n))
+	})
+
+	handle(mux, "POST", "/fetch", func(w http.ResponseWriter, r *http.Request) {
+		var req gitsync.FetchRequest
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var changes []gitsync.GitChange
+		c.Lock()
+		for branch, change := range c.changes {
+			if req.Branch == "" || req.Branch == branch {
+				changes = append(changes, change)
+			}
+		}
+		c.Unlock()
+		if req.Branch != "" && len(changes) == 0 {
+			writeError(w, http.StatusNotFound, "no announcement seen for "+req.Branch)
+			return
+		}
+
+		results := []gitsync.FetchResult{}
+		for _, change := range changes {
+			result := gitsync.FetchResult{Branch: c.self.Naming.Branch(change)}
+			if err := c.fetch(change); err != nil {
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Branch < results[j].Branch })
+		writeJSON(w, http.StatusOK, results)
+	})
+
+	handle(mux, "POST", "/cleanup", func(w http.ResponseWriter, r *http.Request) {
+		c.cleanup()
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+
+	handle(mux, "POST", "/loglevel", func(w http.ResponseWriter, r *http.Request) {
+		var req gitsync.LogLevelRequest
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.actions.SetLevel(req.Level); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Lock()
+		c.record(gitsync.EventLogLevel, "log level set to "+req.Level, nil)
+		c.Unlock()
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+
+	handle(mux, "POST", "/reload", func(w http.ResponseWriter, r *http.Request) {
+		if err := c.reload(); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+
+	return mux
+}
+
+// listenControl listens on the Unix socket at path, only reachable by our
+// user. The socket's directory must be ours and not writable by anyone else,
+// so that no other user can replace the socket, and is made inaccessible to
+// them. A socket left behind by a daemon that did not exit cleanly is
+// replaced, one still in use is not.
+func listenControl(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := checkOwned(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New(path + " is not a socket")
+		}
+		if err = checkOwned(path); err != nil {
+			return nil, err
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.New("another gitsyncd is serving " + path)
+		}
+		os.Remove(path)
+	}
+
+	// other users cannot reach the socket through its directory, even before
+	// it is made ours alone
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// checkOwned returns an error unless path is owned by our user and not
+// writable by anyone else
+func checkOwned(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return errors.New(path + " is not owned by us")
+	}
+	if fi.Mode().Perm()&0022 != 0 {
+		return errors.New(path + " is writable by other users")
+	}
+	return nil
+}
+
+// serveControl serves the control API on listener until it is closed
+func serveControl(listener net.Listener, c *controller) {
+	log.Info("Serving the control API on %s", listener.Addr())
+	if err := http.Serve(listener, c.handler()); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Error("Control API failed: %s", err)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeActions records the actions the control API asks for
+type fakeActions struct {
+	fetched  []gitsync.GitChange
+	fetchErr error
+	cleanups int
+	level    string
+	reloads  int
+	restart  []string // returned by Reload
+	err      error    // returned by SetLevel and Reload
+}
+
+func (f *fakeActions) actions() controlActions {
+	return controlActions{
+		Fetch: func(change gitsync.GitChange) error {
+			f.fetched = append(f.fetched, change)
+			return f.fetchErr
+		},
+		Cleanup: func() {
+			f.cleanups++
+		},
+		SetLevel: func(level string) error {
+			if f.err != nil {
+				return f.err
+			}
+			f.level = level
+			return nil
+		},
+		Reload: func() ([]string, error) {
+			f.reloads++
+			return f.restart, f.err
+		}}
+}
+
+// newTestRepo creates a git repo with a commit on master and on the given
+// branches
+func newTestRepo(t *testing.T, branches ...string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range append([][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "root"}},
+		branchArgs(branches)...) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	return dir
+}
+
+func branchArgs(branches []string) (args [][]string) {
+	for _, b := range branches {
+		args = append(args, []string{"branch", b})
+	}
+	return args
+}
+
+// call makes a request to h and decodes the response into v, if not nil
+func call(t *testing.T, h http.Handler, method, path string, body, v interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: cannot decode %q: %s", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+var (
+	aliceChange = gitsync.GitChange{ID: "1", PeerID: "aaaaaaaa11111111", User: "alice", HostIp: "10.0.0.1", RefName: "topic", Current: "c1"}
+	bobChange   = gitsync.GitChange{ID: "2", PeerID: "bbbbbbbb22222222", User: "bob", HostIp: "10.0.0.2", RefName: "fix", Current: "c2", KeyMismatch: true}
+)
+
+func TestControlPeersAndEvents(t *testing.T) {
+	var f fakeActions
+	ctl := newController(gitsync.Status{Path: t.TempDir()}, nil, newHistory(maxEvents), f.actions())
+	ctl.saw(bobChange)
+	ctl.saw(aliceChange)
+	h := ctl.handler()
+
+	var peers []gitsync.Peer
+	if code := call(t, h, "GET", "/peers", nil, &peers); code != http.StatusOK {
+		t.Fatalf("GET /peers returned %d", code)
+	}
+	if len(peers) != 2 || peers[0].User != "alice" || peers[1].User != "bob" {
+		t.Fatalf("GET /peers returned %+v, want alice then bob", peers)
+	}
+	if peers[0].PeerID != aliceChange.PeerID || peers[0].HostIp != "10.0.0.1" || !peers[1].KeyMismatch {
+		t.Errorf("GET /peers returned %+v", peers)
+	}
+
+	// the checked out branch is kept until another is, or it is left
+	checkedOut := func(refName string, isCheckedOut bool) string {
+		change := aliceChange
+		change.RefName, change.CheckedOut = refName, isCheckedOut
+		ctl.saw(change)
+		call(t, h, "GET", "/peers", nil, &peers)
+		return peers[0].CheckedOut
+	}
+	for _, c := range []struct {
+		refName      string
+		isCheckedOut bool
+		want         string
+	}{
+		{"main", true, "main"},
+		{"topic", false, "main"},
+		{"feature", true, "feature"},
+		{"feature", false, ""},
+	} {
+		if got := checkedOut(c.refName, c.isCheckedOut); got != c.want {
+			t.Errorf("after %s announced with CheckedOut %v, alice has %q checked out, want %q", c.refName, c.isCheckedOut, got, c.want)
+		}
+	}
+
+	var events []gitsync.Event
+	call(t, h, "GET", "/events", nil, &events)
+	if len(events) != 6 {
+		t.Fatalf("GET /events returned %d events, want 6", len(events))
+	}
+	call(t, h, "GET", "/events?limit=1", nil, &events)
+	if len(events) != 1 || events[0].Kind != gitsync.EventChange || events[0].Change == nil || events[0].Change.User != "alice" {
+		t.Errorf("GET /events?limit=1 returned %+v, want alice's change", events)
+	}
+	if code := call(t, h, "GET", "/events?limit=x", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("GET /events?limit=x returned %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestControlEventsAreBounded(t *testing.T) {
+	var f fakeActions
+	ctl := newController(gitsync.Status{Path: t.TempDir()}, nil, newHistory(maxEvents), f.actions())
+	for i := 0; i < maxEvents+10; i++ {
+		ctl.saw(aliceChange)
+	}
+	if n := len(ctl.Events(0)); n != maxEvents {
+		t.Errorf("kept %d events, want %d", n, maxEvents)
+	}
+
+	// events keep their numbers as older ones are dropped
+	var events []gitsync.Event
+	call(t, ctl.handler(), "GET", fmt.Sprintf("/events?since=%d", maxEvents+7), nil, &events)
+	if len(events) != 3 || events[0].Seq != maxEvents+8 || events[2].Seq != maxEvents+10 {
+		t.Errorf("GET /events?since=%d returned %+v, want the last 3 events", maxEvents+7, events)
+	}
+	if events = ctl.EventsSince(0); len(events) != maxEvents || events[0].Seq != 11 {
+		t.Errorf("EventsSince(0) returned %d events from %d, want %d from 11", len(events), events[0].Seq, maxEvents)
+	}
+}
+
+func TestControlFetch(t *testing.T) {
+	var f fakeActions
+	ctl := newController(gitsync.Status{Path: newTestRepo(t)}, nil, newHistory(maxEvents), f.actions())
+	ctl.saw(aliceChange)
+	ctl.saw(bobChange)
+	h := ctl.handler()
+
+	var results []gitsync.FetchResult
+	branch := aliceChange.MirrorBranch()
+	if code := call(t, h, "POST", "/fetch", gitsync.FetchRequest{Branch: branch}, &results); code != http.StatusOK {
+		t.Fatalf("POST /fetch returned %d", code)
+	}
+	if len(f.fetched) != 1 || f.fetched[0] != aliceChange {
+		t.Fatalf("fetched %+v, want alice's change", f.fetched)
+	}
+	if len(results) != 1 || results[0].Branch != branch || results[0].Error != "" {
+		t.Errorf("POST /fetch returned %+v", results)
+	}
+
+	f.fetched, f.fetchErr = nil, errors.New("unreachable")
+	call(t, h, "POST", "/fetch", nil, &results)
+	if len(f.fetched) != 2 || len(results) != 2 || results[0].Error != "unreachable" {
+		t.Errorf("POST /fetch of all branches fetched %+v and returned %+v", f.fetched, results)
+	}
+
+	var mirrors []gitsync.Mirror
+	call(t, h, "GET", "/branches", nil, &mirrors)
+	if len(mirrors) != 2 || mirrors[0].FetchError != "unreachable" {
+		t.Errorf("GET /branches returned %+v, want the fetch errors", mirrors)
+	}
+
+	if code := call(t, h, "POST", "/fetch", gitsync.FetchRequest{Branch: "gitsync-nobody"}, nil); code != http.StatusNotFound {
+		t.Errorf("POST /fetch of an unknown branch returned %d, want %d", code, http.StatusNotFound)
+	}
+	if code := call(t, h, "GET", "/fetch", nil, nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /fetch returned %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestControlBranches(t *testing.T) {
+	var (
+		f       fakeActions
+		fetched = aliceChange.MirrorBranch()
+		stale   = "gitsync-carol-cccccccc-old"
+		ctl     = newController(gitsync.Status{Path: newTestRepo(t, fetched, stale, "feature")}, nil, newHistory(maxEvents), f.actions())
+	)
+	ctl.saw(aliceChange)
+	ctl.saw(bobChange)
+
+	var mirrors []gitsync.Mirror
+	if code := call(t, ctl.handler(), "GET", "/branches", nil, &mirrors); code != http.StatusOK {
+		t.Fatalf("GET /branches returned %d", code)
+	}
+
+	want := map[string]struct{ fetched, announced bool }{
+		fetched:                  {true, true},
+		stale:                    {true, false},
+		bobChange.MirrorBranch(): {false, true},
+	}
+	if len(mirrors) != len(want) {
+		t.Fatalf("GET /branches returned %+v, want %d branches", mirrors, len(want))
+	}
+	for _, m := range mirrors {
+		w, ok := want[m.Branch]
+		if !ok {
+			t.Errorf("unexpected branch %s", m.Branch)
+			continue
+		}
+		if (m.Current != "") != w.fetched || (m.Change != nil) != w.announced {
+			t.Errorf("branch %+v, want fetched %v and announced %v", m, w.fetched, w.announced)
+		}
+		if w.fetched && (m.Tip == nil || m.Tip.Hash != m.Current || m.Tip.Subject != "root" || m.Tip.Time.IsZero()) {
+			t.Errorf("branch %s has tip %+v, want the root commit", m.Branch, m.Tip)
+		}
+	}
+}
+
+func TestControlStatus(t *testing.T) {
+	var (
+		f   fakeActions
+		n   = gitsync.NewMemoryNetwork()
+		ctl = newController(gitsync.Status{User: "carol", Path: "/src/repo", PeerID: "cccccccc33333333"}, []gitsync.Transport{n.Transport("10.0.0.3")}, newHistory(maxEvents), f.actions())
+	)
+	ctl.saw(aliceChange)
+
+	var status gitsync.Status
+	if code := call(t, ctl.handler(), "GET", "/status", nil, &status); code != http.StatusOK {
+		t.Fatalf("GET /status returned %d", code)
+	}
+	if status.User != "carol" || status.Path != "/src/repo" || status.Peers != 1 {
+		t.Errorf("GET /status returned %+v", status)
+	}
+	if len(status.Transports) != 1 || status.Transports[0].Name != "memory 10.0.0.3" || !status.Transports[0].Up {
+		t.Errorf("GET /status returned transports %+v", status.Transports)
+	}
+}
+
+func TestControlActions(t *testing.T) {
+	var f fakeActions
+	ctl := newController(gitsync.Status{Path: t.TempDir()}, nil, newHistory(maxEvents), f.actions())
+	h := ctl.handler()
+
+	if code := call(t, h, "POST", "/cleanup", nil, nil); code != http.StatusOK || f.cleanups != 1 {
+		t.Errorf("POST /cleanup returned %d and cleaned up %d times", code, f.cleanups)
+	}
+	if code := call(t, h, "POST", "/loglevel", gitsync.LogLevelRequest{Level: "debug"}, nil); code != http.StatusOK || f.level != "debug" {
+		t.Errorf("POST /loglevel returned %d and set level %q", code, f.level)
+	}
+	if code := call(t, h, "POST", "/reload", nil, nil); code != http.StatusOK || f.reloads != 1 {
+		t.Errorf("POST /reload returned %d and reloaded %d times", code, f.reloads)
+	}
+	f.restart = []string{"transport", "webport"}
+	call(t, h, "POST", "/reload", nil, nil)
+	if events := ctl.Events(1); len(events) != 1 || !strings.Contains(events[0].Message, "restart for changes to transport, webport") {
+		t.Errorf("reload needing a restart recorded %+v", events)
+	}
+
+	f.err = errors.New("bad")
+	var e gitsync.ControlError
+	if code := call(t, h, "POST", "/loglevel", gitsync.LogLevelRequest{Level: "loud"}, &e); code != http.StatusBadRequest || e.Error != "bad" {
+		t.Errorf("POST /loglevel of a bad level returned %d %+v", code, e)
+	}
+	if code := call(t, h, "POST", "/reload", nil, &e); code != http.StatusInternalServerError || e.Error != "bad" {
+		t.Errorf("failed POST /reload returned %d %+v", code, e)
+	}
+
+	kinds := []string{}
+	for _, event := range ctl.Events(0) {
+		kinds = append(kinds, event.Kind)
+	}
+	if got, want := strings.Join(kinds, " "), "cleanup loglevel reload reload"; got != want {
+		t.Errorf("recorded events %s, want %s", got, want)
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	var (
+		f    fakeActions
+		path = filepath.Join(t.TempDir(), ".git", "gitsync", "control.sock")
+		ctl  = newController(gitsync.Status{Path: t.TempDir()}, nil, newHistory(maxEvents), f.actions())
+	)
+	ctl.saw(aliceChange)
+
+	// a socket left behind by a daemon that died is replaced, in a directory
+	// others could read until it is listened in
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	if _, err = os.Stat(path); err != nil {
+		t.Fatalf("stale socket is gone: %s", err)
+	}
+
+	listener, err := listenControl(path)
+	if err != nil {
+		t.Fatalf("cannot listen on %s: %s", path, err)
+	}
+	defer listener.Close()
+	go serveControl(listener, ctl)
+
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("socket mode is %v (%v), want 0600", fi.Mode().Perm(), err)
+	}
+	if fi, err := os.Stat(filepath.Dir(path)); err != nil || fi.Mode().Perm() != 0700 {
+		t.Errorf("socket directory mode is %v (%v), want 0700", fi.Mode().Perm(), err)
+	}
+	if _, err := listenControl(path); err == nil {
+		t.Errorf("a second daemon could serve %s", path)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		}}}
+	resp, err := client.Get("http://gitsyncd/peers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var peers []gitsync.Peer
+	if err = json.NewDecoder(resp.Body).Decode(&peers); err != nil || len(peers) != 1 || peers[0].User != "alice" {
+		t.Errorf("GET /peers over the socket returned %+v (%v)", peers, err)
+	}
+}
+
+func TestControlSocketRefused(t *testing.T) {
+	// a directory others can write to lets them replace the socket
+	shared := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(shared, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 01777); err != nil {
+		t.Fatal(err)
+	}
+	if listener, err := listenControl(filepath.Join(shared, "control.sock")); err == nil {
+		listener.Close()
+		t.Errorf("listened in world-writable %s", shared)
+	}
+
+	// whatever is at the path, if not a socket, is left alone
+	path := filepath.Join(t.TempDir(), "control.sock")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if listener, err := listenControl(path); err == nil {
+		listener.Close()
+		t.Errorf("replaced file %s with a socket", path)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("file %s was changed: %q (%v)", path, data, err)
+	}
+}
+
+func TestControlSocketPath(t *testing.T) {
+	if got, want := gitsync.ControlSocketPath("/src/repo"), "/src/repo/.git/gitsync/control.sock"; got != want {
+		t.Errorf("socket for /src/repo is %s, want %s", got, want)
+	}
+
+	long := "/" + strings.Repeat("deep/", 30) + "repo"
+	path := gitsync.ControlSocketPath(long)
+	if len(path) > 103 || strings.HasPrefix(path, long) {
+		t.Errorf("socket for a deep repo is %s", path)
+	}
+	if other := gitsync.ControlSocketPath(long + "2"); other == path {
+		t.Errorf("repos %s and %s share socket %s", long, long+"2", path)
+	}
+}
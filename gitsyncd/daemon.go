@@ -1,19 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
 	log "github.com/ngmoco/timber"
 	"github.com/raybejjani/gitsync/gitsync"
 	"github.com/raybejjani/gitsync/util"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
 	"os/signal"
 	"os/user"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -38,27 +43,55 @@ func unixSyslog() (network, addr string, err error) {
 	return "", "", errors.New("Unix syslog delivery error")
 }
 
+// version is that of gitsyncd, set when building with the Makefile
+var version = "dev"
+
+// loggers are the loggers added by setupLogging, by their index in log.Global,
+// so their level can be changed
+var loggers = make(map[int]log.ConfigLogger)
+
+// parseLogLevel returns the level named logLevel, or DEBUG if it is empty
+func parseLogLevel(logLevel string) (log.Level, error) {
+	if logLevel == "" {
+		return log.DEBUG, nil
+	}
+	for idx, str := range log.LongLevelStrings {
+		if idx != 0 && strings.EqualFold(str, logLevel) {
+			return log.Level(idx), nil
+		}
+	}
+	return log.Level(0), fmt.Errorf("Cannot parse log level %s", logLevel)
+}
+
+// setLogLevel changes the lowest level logged by the loggers set up by
+// setupLogging
+func setLogLevel(logLevel string) error {
+	level, err := parseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	for idx, logger := range loggers {
+		logger.Level = level
+		loggers[idx] = logger
+		log.Global.SetLogger(idx, logger)
+	}
+	log.Info("Log level set to %s", logLevel)
+	return nil
+}
+
 // setupLogging initialises logging per the parameters:
 // logLevel: lowest level to log
 // logSocket: socket to log to (defaults to the system syslog)
 // logFile: file to log to
 func setupLogging(logLevel, logSocket, logFile string) (err error) {
 	var (
-		network string      // the network type to pass Dial
-		address string      // the address to Dial to
-		level   = log.DEBUG // the lowest level to log
+		network string // the network type to pass Dial
+		address string // the address to Dial to
 	)
 
-	if logLevel != "" {
-		level = log.Level(0)
-		for idx, str := range log.LongLevelStrings {
-			if strings.EqualFold(str, logLevel) {
-				level = log.Level(idx)
-			}
-		}
-		if level == log.Level(0) {
-			return fmt.Errorf("Cannot parse log level %s", logLevel)
-		}
+	level, err := parseLogLevel(logLevel)
+	if err != nil {
+		return err
 	}
 
 	if logSocket == "" {
@@ -79,11 +112,12 @@ func setupLogging(logLevel, logSocket, logFile string) (err error) {
 			return err
 		}
 		// add console output for logs
-		log.AddLogger(log.ConfigLogger{
+		logger := log.ConfigLogger{
 			LogWriter: writer,
 			Level:     level,
 			Formatter: log.NewSyslogFormatter("[%L] %s %M"),
-		})
+		}
+		loggers[log.AddLogger(logger)] = logger
 	}
 
 	if logFile != "" {
@@ -91,43 +125,167 @@ func setupLogging(logLevel, logSocket, logFile string) (err error) {
 		if writer, err = log.NewFileWriter(logFile); err != nil {
 			return err
 		}
-		log.AddLogger(log.ConfigLogger{
+		logger := log.ConfigLogger{
 			LogWriter: writer,
 			Level:     level,
 			Formatter: log.NewPatFormatter("[%D %T][%L] %s %M"),
-		})
+		}
+		loggers[log.AddLogger(logger)] = logger
 	}
 
 	return
 }
 
+// secretEnvVar is consulted for the shared secret when no secret file is given
+const secretEnvVar = "GITSYNC_SECRET"
+
+// loadKeys reads the team's shared secrets from secretFile, one per line, or if
+// that is empty, whitespace separated from the environment. The first key is
+// used to send messages and all are accepted, allowing keys to be rotated. No
+// keys means messages are not authenticated.
+func loadKeys(secretFile string) (keys [][]byte, err error) {
+	if secretFile == "" {
+		for _, key := range strings.Fields(os.Getenv(secretEnvVar)) {
+			keys = append(keys, []byte(key))
+		}
+		return keys, nil
+	}
+
+	data, err := ioutil.ReadFile(secretFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if line = bytes.TrimSpace(line); len(line) > 0 && line[0] != '#' {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no keys", secretFile)
+	}
+	return keys, nil
+}
+
+// loadPeers returns the unicast peers given in peers, a comma separated list
+// of host:port, and in peersFile, which lists one host:port per line
+func loadPeers(peers, peersFile string) (list []string, err error) {
+	for _, peer := range strings.Split(peers, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			list = append(list, peer)
+		}
+	}
+	if peersFile == "" {
+		return list, nil
+	}
+
+	data, err := ioutil.ReadFile(peersFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+			list = append(list, line)
+		}
+	}
+	return list, nil
+}
+
+// reload re-reads the shared secrets into auth, and the peers files into the
+// unicast transport, as asked for over the control API or with SIGHUP. Peers
+// removed from the file are kept until they go quiet.
+func reload(auth *gitsync.Authenticator, secretFile string, unicast gitsync.Transport, peers, peersFile string) error {
+	if auth != nil {
+		keys, err := loadKeys(secretFile)
+		if err != nil {
+			return fmt.Errorf("cannot read shared secret: %s", err)
+		}
+		if err = auth.SetKeys(keys); err != nil {
+			return fmt.Errorf("cannot use shared secret: %s", err)
+		}
+		log.Info("Reloaded %d shared secret(s)", len(keys))
+	}
+
+	if adder, ok := unicast.(gitsync.PeerAdder); ok {
+		list, err := loadPeers(peers, peersFile)
+		if err != nil {
+			return fmt.Errorf("cannot read peers: %s", err)
+		}
+		for _, peer := range list {
+			adder.AddPeer(peer)
+		}
+		log.Info("Reloaded %d peer(s)", len(list))
+	}
+	return nil
+}
+
+// gitsyncHome returns the directory holding the per-user gitsync state, such
+// as our identity and the keys of known peers
+func gitsyncHome() string {
+	if u, err := user.Current(); err == nil {
+		return path.Join(u.HomeDir, ".gitsync")
+	}
+	return path.Join(os.Getenv("HOME"), ".gitsync")
+}
+
 // fatalf logs a fatal error and exits
 func fatalf(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format+"\n", args...)
 	log.Fatalf(format, args...)
 }
 
-func fetchChange(change gitsync.GitChange, dirName string) error {
-	// We force a fetch from the change's source to a local branch
-	// named gitsync-<remote username>-<remote branch name>
-	localBranchName := fmt.Sprintf(
-		"gitsync-%s-%s", change.User, change.RefName)
+// urlHost formats an IP address for use as the host part of a URL, bracketing
+// IPv6 addresses
+func urlHost(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]"
+	}
+	return ip
+}
+
+func fetchChange(change gitsync.GitChange, dirName string, naming gitsync.MirrorNaming, auth *gitsync.Authenticator) error {
+	var (
+		args []string // git arguments preceding the fetch
+		env  []string // git environment, nil to inherit ours
+		host = urlHost(change.HostIp)
+	)
+
+	// Encrypted fetches go through a copy of ourselves run as git's proxy
+	// command
+	if change.FetchPort != 0 {
+		if auth == nil {
+			return fmt.Errorf("%s serves encrypted fetches but we have no shared secret", change.User)
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		host = net.JoinHostPort(change.HostIp, strconv.Itoa(change.FetchPort))
+		args = []string{"-c", "core.gitProxy=" + exe}
+		env = append(os.Environ(), proxyKeyEnvVar+"="+hex.EncodeToString(auth.Key()))
+	}
+
+	// We force a fetch from the change's source to a local branch named by
+	// naming, by default gitsync-<remote username>-<remote peer id>-<remote
+	// branch name>. The user and branch names come from the network, so both
+	// branch names are checked before they reach git
+	localBranchName := naming.Branch(change)
+	if err := gitsync.CheckRefName(change.RefName); err != nil {
+		return err
+	}
+	if err := gitsync.CheckRefName(localBranchName); err != nil {
+		return err
+	}
 	fetchUrl := fmt.Sprintf(
-		"git://%s/%s", change.HostIp, change.RepoName)
-	cmd := exec.Command("git", "fetch", "-f", fetchUrl,
-		fmt.Sprintf("%s:%s", change.RefName, localBranchName))
+		"git://%s/%s", host, change.RepoName)
+	cmd := exec.Command("git", append(args, "fetch", "-f", "--end-of-options", fetchUrl,
+		fmt.Sprintf("%s:%s", change.RefName, localBranchName))...)
 	cmd.Dir = dirName
+	cmd.Env = env
 	err := cmd.Run()
 	return err
 }
 
-func ReceiveChanges(changes chan gitsync.GitChange, webPort uint16, repo gitsync.Repo) {
-	log.Info("webport %d", webPort)
-	var webEvents = make(chan *gitsync.GitChange, 128)
-	if webPort != 0 {
-		go serveWeb(webPort, webEvents)
-	}
-
+func ReceiveChanges(changes chan gitsync.GitChange, repo gitsync.Repo, ctl *controller, conf *settings, naming gitsync.MirrorNaming) {
 	for {
 		select {
 		case change, ok := <-changes:
@@ -137,26 +295,26 @@ func ReceiveChanges(changes chan gitsync.GitChange, webPort uint16, repo gitsync
 			}
 
 			log.Info("saw %+v", change)
-			if change.FromRepo(repo) {
-				if err := fetchChange(change, repo.Path()); err != nil {
+			ctl.saw(change)
+			if change.KeyMismatch {
+				log.Warn("Not fetching from %s, whose key is not approved. See 'gitsyncd keys'", change.User)
+			} else if change.FromRepo(repo) {
+				if !conf.fetches(change) {
+					log.Debug("Not fetching %s, per the fetch rules", naming.Branch(change))
+				} else if err := ctl.fetch(change); err != nil {
 					log.Info("Error fetching change")
 				} else {
 					log.Info("fetched change")
 				}
-			}
-
-			if webPort != 0 {
-				select {
-				case webEvents <- &change:
-				default:
-					log.Info("Dropped event %+v from websocket")
-				}
+				conf.notify(change, naming.Branch(change))
 			}
 		}
 	}
 }
 
-func startGitDaemon(absolutePath string) error {
+// startGitDaemon serves the repo over git://. With localOnly set it only
+// listens on the loopback interface, for use behind the encrypted fetch proxy.
+func startGitDaemon(absolutePath string, localOnly bool) error {
 	daemonSentinel := path.Join(absolutePath, ".git",
 		"git-daemon-export-ok")
 	if _, err := os.Stat(daemonSentinel); os.IsNotExist(err) {
@@ -165,50 +323,122 @@ func startGitDaemon(absolutePath string) error {
 			log.Fatalf("Unable to set up git daemon")
 		}
 	}
-	cmd := exec.Command("git", "daemon", "--reuseaddr",
-		fmt.Sprintf("--base-path=%s/..", absolutePath),
-		absolutePath)
+	args := []string{"daemon", "--reuseaddr",
+		fmt.Sprintf("--base-path=%s/..", absolutePath)}
+	if localOnly {
+		host, port, _ := net.SplitHostPort(gitDaemonAddr)
+		args = append(args, "--listen="+host, "--port="+port)
+	}
+	cmd := exec.Command("git", append(args, absolutePath)...)
 	err := cmd.Start()
 	return err
 }
 
-func cleanup(dirName string) {
-	// Delete all branches that begin with 'gitsync-'
-	getBranches := exec.Command("git", "branch")
-	getGitsyncBranches := exec.Command("grep", "gitsync-")
-	deleteGitsyncBranches := exec.Command("xargs", "git", "branch", "-D")
-	getBranches.Dir = dirName
-	getGitsyncBranches.Dir = dirName
-	deleteGitsyncBranches.Dir = dirName
-	getGitsyncBranches.Stdin, _ = getBranches.StdoutPipe()
-	deleteGitsyncBranches.Stdin, _ = getGitsyncBranches.StdoutPipe()
-	err := deleteGitsyncBranches.Start()
-	err = getGitsyncBranches.Start()
-	err = getBranches.Run()
-	err = getGitsyncBranches.Wait()
-	err = deleteGitsyncBranches.Wait()
-
-	if err != nil {
-		log.Info("Could not delete gitsync branches ", err)
+// cleanup deletes the mirror branches, as named by naming
+func cleanup(dirName string, naming gitsync.MirrorNaming) {
+	branches, err := mirrorBranches(dirName, naming)
+	if err != nil || len(branches) == 0 {
+		return
+	}
+	args := []string{"branch", "-D"}
+	for branch := range branches {
+		args = append(args, branch)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirName
+	if err = cmd.Run(); err != nil {
+		log.Info("Could not delete gitsync branches %s", err)
 	}
 }
 
 func main() {
+	// git runs us as core.gitProxy, with the host and port to connect to, when
+	// fetching from a peer that encrypts fetches
+	if key := os.Getenv(proxyKeyEnvVar); key != "" && len(os.Args) == 3 {
+		if err := runFetchProxy(os.Args[1], os.Args[2], key); err != nil {
+			fmt.Fprintf(os.Stderr, "gitsyncd: fetch proxy: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Start changes handler
 	var (
-		username  = flag.String("user", "", "Username to report when sending changes to the network")
-		groupIP   = flag.String("ip", gitsync.IP4MulticastAddr.IP.String(), "Multicast IP to connect to")
-		groupPort = flag.Int("port", gitsync.IP4MulticastAddr.Port, "Port to use for network IO")
-		logLevel  = flag.String("loglevel", "info", "Lowest log level to emit. Can be one of debug, info, warning, error.")
-		logSocket = flag.String("logsocket", "", "proto://address:port target to send logs to")
-		logFile   = flag.String("logfile", "", "path to file to log to")
-		webPort   = flag.Int("webport", 0, "Port for local webserver. Off by default")
+		username   = flag.String("user", "", "Username to report when sending changes to the network")
+		groupIP    = flag.String("ip", gitsync.IP4MulticastAddr.IP.String(), "IPv4 multicast group to join, empty to not use IPv4")
+		groupIP6   = flag.String("ip6", gitsync.IP6MulticastAddr.IP.String(), "IPv6 multicast group to join, empty to not use IPv6")
+		groupPort  = flag.Int("port", gitsync.IP4MulticastAddr.Port, "Port to use for network IO")
+		iface      = flag.String("iface", "", "Interface, by name or by a CIDR one of its addresses is in, to use for multicast. Defaults to the system's choice")
+		ttl        = flag.Int("ttl", 1, "TTL (IPv4) and hop limit (IPv6) of multicast messages. 0 uses the system default")
+		loopback   = flag.Bool("loopback", true, "Deliver our multicast messages to other daemons on this machine")
+		channel    = flag.String("channel", "", "Team or channel name. Only peers on the same channel are synced with")
+		chanGroup  = flag.Bool("channelgroup", false, "Use multicast groups derived from -channel rather than -ip and -ip6")
+		gossip     = flag.Bool("gossip", false, "Forward peers' announcements between transports, to bridge networks")
+		gossipTTL  = flag.Int("gossipttl", gitsync.DefaultGossipTTL, "How many gossiping peers our announcements may be forwarded by")
+		transport  = flag.String("transport", "multicast", "Comma separated ways to reach peers. Can be any of multicast, unicast, relay")
+		uniProto   = flag.String("unicast", "udp", "Protocol to send unicast messages with. Can be one of udp, tcp")
+		uniPort    = flag.Int("unicastport", 9998, "Port to listen on for unicast messages")
+		peers      = flag.String("peers", "", "Comma separated host:port of peers to send unicast messages to")
+		uniPeers   = flag.String("peersfile", "", "File listing host:port of peers to send unicast messages to, one per line")
+		relayAddr  = flag.String("relayaddr", "", "host:port of the relay to use with the relay transport")
+		mdns       = flag.Bool("mdns", false, "Advertise the repo as a "+gitsync.ServiceType+" DNS-SD service over mDNS, and browse for peers advertising it. The advertisement is not encrypted")
+		relay      = flag.Bool("relay", false, "Run as a relay, forwarding announcements between the daemons that connect to it, rather than watching a repo")
+		relayPort  = flag.Int("relayport", 9997, "Port to listen on when running as a relay")
+		logLevel   = flag.String("loglevel", "info", "Lowest log level to emit. Can be one of debug, info, warning, error.")
+		logSocket  = flag.String("logsocket", "", "proto://address:port target to send logs to")
+		logFile    = flag.String("logfile", "", "path to file to log to")
+		webPort    = flag.Int("webport", 0, "Port for local webserver. Off by default")
+		webAddr    = flag.String("webaddr", "127.0.0.1", "Address the local webserver listens on. It is not authenticated, so only give one reachable by others, e.g. 0.0.0.0, to share it with them")
+		secretFile = flag.String("secretfile", "", "File holding the team's shared secrets used to authenticate messages, one per line. The first is used to send. Defaults to $"+secretEnvVar)
+		encrypt    = flag.Bool("encrypt", false, "Encrypt messages with the shared secret")
+		encFetch   = flag.Bool("encryptfetch", false, "Only serve fetches over a channel encrypted with the shared secret")
+		fetchPort  = flag.Int("fetchport", 9419, "Port to serve encrypted fetches on")
+		peerIDFile = flag.String("peerid", path.Join(gitsyncHome(), "peer_id"), "File holding the ID telling this installation apart from others, generated if missing")
+		idFile     = flag.String("identity", path.Join(gitsyncHome(), "identity"), "File holding our signing key, generated if missing")
+		peersFile  = flag.String("knownpeers", path.Join(gitsyncHome(), "known_peers"), "File pinning the signing key of each peer")
+		strict     = flag.Bool("strictpeers", false, "Drop announcements signed with a key not approved for the user, rather than just not fetching them")
+		ctlSocket  = flag.String("controlsocket", "", "Unix socket to serve the control API on. Defaults to .git/gitsync/control.sock in the repo")
+		poll       = flag.Duration("poll", time.Second, "How often to look for changes to the repo's branches")
+		prefix     = flag.String("branchprefix", gitsync.DefaultMirrorPrefix, "Start of the names of the local branches peers' branches are fetched into")
+		branchName = flag.String("branchname", gitsync.DefaultMirrorTemplate, "Rest of the names of the local branches peers' branches are fetched into. {user}, {peer}, {repo} and {branch} are replaced")
+		configFile = flag.String("config", "", "Config file to read, rather than "+systemConfig+", gitsync/config in the user's config directory and .git/gitsync/config in the repo. The repo's "+projectConfig+" is read either way")
+		share      = flag.String("share", "", "Comma separated patterns of the branches to announce, e.g. main,feature/*. Defaults to all of them")
+		repoID     = flag.String("repoid", "", "ID telling the repo apart from others, the same for all peers. Defaults to its root commit")
+		ignorePath = flag.String("ignorepaths", "", "Comma separated patterns of paths, e.g. docs,*.md, whose changes alone do not trigger notify rules")
+		histSize   = flag.Int("history", maxEvents, "Number of recent events kept, and replayed to new web clients")
+		histFile   = flag.String("historyfile", "", "File to keep the recent events in, so that they survive restarts. Off by default")
+		slowPolicy = flag.String("slowclients", slowDisconnect, "What to do with web clients too slow to keep up with the events. Can be one of "+slowDrop+", to drop the events that do not fit in their queue, or "+slowDisconnect+", for them to resume once they reconnect")
 	)
 	flag.Parse()
 
-	if len(flag.Args()) == 0 {
+	// the config files set the flags not given on the command line. The repo
+	// may have one of its own, read once we know which repo to watch.
+	conf := newSettings(*configFile, ignorePath)
+	if err := conf.load(""); err != nil {
+		fatalf("Cannot read config: %s", err)
+	}
+
+	if flag.Arg(0) == "keys" {
+		if err := runKeysCommand(flag.Args()[1:], *idFile, *peersFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	dirName := flag.Arg(0) // directory to watch
+	if dirName == "" {
+		dirName = conf.repo()
+	}
+	if dirName == "" && !*relay {
 		fatalf("No Git directory supplied")
 	}
+	if dirName != "" {
+		dirName = util.AbsPath(dirName)
+		if err := conf.load(dirName); err != nil {
+			fatalf("Cannot read config: %s", err)
+		}
+	}
 
 	if err := setupLogging(*logLevel, *logSocket, *logFile); err != nil {
 		fatalf("Cannot setup logging: %s", err)
@@ -217,17 +447,29 @@ func main() {
 	log.Info("Starting up")
 	defer log.Info("Exiting")
 
+	if *relay {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *relayPort))
+		if err != nil {
+			fatalf("Cannot listen for daemons: %s", err)
+		}
+		log.Info("Relaying announcements on %d", *relayPort)
+		if err = gitsync.ServeRelay(log.Global, listener); err != nil {
+			fatalf("Relay failed: %s", err)
+		}
+		return
+	}
+
 	var (
-		err       error
-		dirName   = flag.Args()[0] // directories to watch
-		userId    string           // username
-		groupAddr *net.UDPAddr     // network address to connect to
+		err    error
+		userId string                                                         // username
+		groups []*net.UDPAddr                                                 // multicast groups to join
+		auth   *gitsync.Authenticator                                         // authenticates messages, nil if no secret is set
+		naming = gitsync.MirrorNaming{Prefix: *prefix, Template: *branchName} // names the mirror branches
 
 		// channels to move change messages around
 		remoteChanges   = make(chan gitsync.GitChange, 128)
 		toRemoteChanges = make(chan gitsync.GitChange, 128)
 	)
-	dirName = util.AbsPath(dirName)
 
 	// get the user's name
 	if *username != "" {
@@ -238,29 +480,227 @@ func main() {
 		fatalf("Cannot get username: %v", err)
 	}
 
-	if groupAddr, err = net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", *groupIP, *groupPort)); err != nil {
-		fatalf("Cannot resolve address %v:%v: %v", *groupIP, *groupPort, err)
+	transports := make(map[string]bool)
+	for _, t := range strings.Split(*transport, ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "multicast", "unicast", "relay":
+			transports[t] = true
+		default:
+			fatalf("Unknown transport %s", t)
+		}
+	}
+
+	if err = naming.Check(); err != nil {
+		fatalf("Bad -branchname: %s", err)
+	}
+	if *slowPolicy != slowDrop && *slowPolicy != slowDisconnect {
+		fatalf("Bad -slowclients %s, must be %s or %s", *slowPolicy, slowDrop, slowDisconnect)
+	}
+
+	if *gossipTTL < 1 || *gossipTTL > 255 {
+		fatalf("-gossipttl must be between 1 and 255")
+	}
+
+	if *chanGroup {
+		if *channel == "" {
+			fatalf("-channelgroup needs a -channel")
+		}
+		ip4, ip6 := gitsync.ChannelGroups(*channel, *groupPort)
+		if *groupIP != "" {
+			*groupIP = ip4.IP.String()
+		}
+		if *groupIP6 != "" {
+			*groupIP6 = ip6.IP.String()
+		}
+	}
+
+	if transports["multicast"] {
+		for _, group := range []struct{ network, ip string }{{"udp4", *groupIP}, {"udp6", *groupIP6}} {
+			if group.ip == "" {
+				continue
+			}
+			addr, err := net.ResolveUDPAddr(group.network, net.JoinHostPort(group.ip, strconv.Itoa(*groupPort)))
+			if err != nil {
+				fatalf("Cannot resolve address %v:%v: %v", group.ip, *groupPort, err)
+			}
+			groups = append(groups, addr)
+		}
+		if len(groups) == 0 {
+			fatalf("No multicast group to join, set -ip and/or -ip6")
+		}
+	}
+
+	if keys, err := loadKeys(*secretFile); err != nil {
+		fatalf("Cannot read shared secret: %s", err)
+	} else if len(keys) > 0 {
+		if auth, err = gitsync.NewAuthenticator(keys, *encrypt, gitsync.DefaultReplayWindow); err != nil {
+			fatalf("Cannot set up message authentication: %s", err)
+		}
+	} else if *encrypt || *encFetch {
+		fatalf("Encryption needs a shared secret, see -secretfile")
+	} else {
+		log.Warn("No shared secret set, messages will not be authenticated")
 	}
 
 	// start directory poller
-	repo, err := gitsync.NewCliRepo(userId, dirName)
-	if err != nil {
+	var repo gitsync.Repo
+	if repo, err = gitsync.NewCliRepo(userId, dirName); err != nil {
 		fatalf("Cannot open repo: %s", err)
 	}
+	if *repoID != "" {
+		repo = gitsync.WithRepoID(repo, *repoID)
+	}
+
+	netCfg := gitsync.NetConfig{
+		Multicast: gitsync.MulticastOptions{
+			Interface: *iface,
+			TTL:       *ttl,
+			Loopback:  *loopback},
+		Channel:     *channel,
+		Gossip:      *gossip,
+		GossipTTL:   *gossipTTL,
+		Auth:        auth,
+		MDNS:        *mdns,
+		StrictPeers: *strict}
+	if netCfg.PeerID, err = gitsync.LoadPeerID(*peerIDFile); err != nil {
+		fatalf("Cannot load peer ID: %s", err)
+	}
+	log.Info("Running as peer %s", netCfg.PeerID)
+	if netCfg.Identity, err = gitsync.LoadIdentity(*idFile); err != nil {
+		fatalf("Cannot load identity: %s", err)
+	}
+	log.Info("Signing announcements with key %s", netCfg.Identity)
+	if netCfg.KnownPeers, err = gitsync.LoadKnownPeers(*peersFile); err != nil {
+		fatalf("Cannot load known peers: %s", err)
+	}
+	if *encFetch {
+		netCfg.FetchPort = *fetchPort
+	}
+	if transports["multicast"] {
+		t, err := gitsync.NewMulticastTransport(log.Global, groups, netCfg.Multicast)
+		if err != nil {
+			fatalf("Cannot use multicast: %s", err)
+		}
+		netCfg.Transports = append(netCfg.Transports, t)
+	}
+	var unicast gitsync.Transport
+	if transports["unicast"] {
+		list, err := loadPeers(*peers, *uniPeers)
+		if err != nil {
+			fatalf("Cannot read peers: %s", err)
+		}
+		unicast, err = gitsync.NewUnicastTransport(gitsync.UnicastOptions{
+			Proto: *uniProto,
+			Port:  *uniPort,
+			Peers: list})
+		if err != nil {
+			fatalf("Cannot listen for unicast: %s", err)
+		}
+		netCfg.Transports = append(netCfg.Transports, unicast)
+	}
+	if transports["relay"] {
+		if *relayAddr == "" {
+			fatalf("The relay transport needs -relayaddr")
+		}
+		rootCommit, err := repo.RootCommit()
+		if err != nil {
+			fatalf("Cannot use a relay without a root commit: %s", err)
+		}
+		netCfg.Transports = append(netCfg.Transports, gitsync.NewRelayTransport(*relayAddr, *channel, rootCommit))
+	}
 
-	if err = startGitDaemon(dirName); err != nil {
+	if err = startGitDaemon(dirName, *encFetch); err != nil {
 		log.Fatalf("Unable to start git daemon")
 	}
+	if *encFetch {
+		go serveSecureFetch(*fetchPort, auth)
+	}
 
-	go gitsync.PollDirectory(log.Global, dirName, repo, toRemoteChanges, 1*time.Second)
-	go gitsync.NetIO(log.Global, repo, groupAddr, remoteChanges, toRemoteChanges)
-	go ReceiveChanges(remoteChanges, uint16(*webPort), repo)
+	rootCommit, _ := repo.RootCommit()
+	self := gitsync.Status{
+		User:    userId,
+		Path:    dirName,
+		PeerID:  netCfg.PeerID,
+		RepoID:  rootCommit,
+		Started: time.Now(),
+		Naming:  naming}
+	if *histSize <= 0 {
+		fatalf("-history must be positive")
+	}
+	events := newHistory(*histSize)
+	if *histFile != "" {
+		if err = events.persist(*histFile); err != nil {
+			fatalf("Cannot keep events in %s: %s", *histFile, err)
+		}
+		defer events.close()
+	}
+	var reloadLock sync.Mutex // serialises reloads, which set the flags
+	ctl := newController(self, netCfg.Transports, events, controlActions{
+		Fetch: func(change gitsync.GitChange) error {
+			return fetchChange(change, dirName, naming, auth)
+		},
+		Cleanup: func() {
+			cleanup(dirName, naming)
+		},
+		SetLevel: setLogLevel,
+		Reload: func() ([]string, error) {
+			reloadLock.Lock()
+			defer reloadLock.Unlock()
+
+			level := *logLevel
+			restart, err := conf.reload()
+			if err != nil {
+				return nil, fmt.Errorf("cannot read config: %s", err)
+			}
+			if *logLevel != level {
+				if err := setLogLevel(*logLevel); err != nil {
+					return nil, err
+				}
+			}
+			return restart, reload(auth, *secretFile, unicast, *peers, *uniPeers)
+		}})
+	if *ctlSocket == "" {
+		*ctlSocket = gitsync.ControlSocketPath(dirName)
+	}
+	if listener, err := listenControl(*ctlSocket); err != nil {
+		log.Error("Cannot serve the control API on %s: %s", *ctlSocket, err)
+	} else {
+		defer listener.Close()
+		go serveControl(listener, ctl)
+	}
+
+	var shared []string // patterns of the branches announced, empty for all
+	for _, pattern := range strings.Split(*share, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			shared = append(shared, pattern)
+		}
+	}
+	skip := func(branch string) bool {
+		return naming.IsMirror(branch) || (len(shared) > 0 && !matchAny(shared, branch))
+	}
+	go gitsync.PollDirectory(log.Global, dirName, repo, toRemoteChanges, *poll, skip)
+	go func() {
+		if err := gitsync.NetIO(log.Global, repo, netCfg, remoteChanges, toRemoteChanges); err != nil {
+			fatalf("Cannot share changes: %s", err)
+		}
+	}()
+	log.Info("webport %d", *webPort)
+	if *webPort != 0 {
+		go serveWeb(*webAddr, uint16(*webPort), ctl, *slowPolicy)
+	}
+	go ReceiveChanges(remoteChanges, repo, ctl, conf, naming)
 
 	s := make(chan os.Signal, 1)
-	signal.Notify(s, os.Kill, os.Interrupt, syscall.SIGUSR1)
+	signal.Notify(s, os.Kill, os.Interrupt, syscall.SIGUSR1, syscall.SIGHUP)
 	for {
 		c := <-s
-		cleanup(dirName)
+		if c == syscall.SIGHUP {
+			if err := ctl.reload(); err != nil {
+				log.Error("Cannot reload the configuration: %s", err)
+			}
+			continue
+		}
+		ctl.cleanup()
 		if (c == os.Kill) || (c == os.Interrupt) {
 			break
 		}
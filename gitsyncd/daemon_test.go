@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/raybejjani/gitsync/gitsync"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchChangeRefusesBadNames(t *testing.T) {
+	dir := newTestRepo(t)
+	for _, change := range []gitsync.GitChange{
+		{User: "alice", PeerID: "aaaaaaaa11111111", HostIp: "127.0.0.1", RepoName: "repo", RefName: "--upload-pack=touch pwned"},
+		{User: "alice", PeerID: "aaaaaaaa11111111", HostIp: "127.0.0.1", RepoName: "repo", RefName: "a..b"},
+		{User: "al..ice", PeerID: "aaaaaaaa11111111", HostIp: "127.0.0.1", RepoName: "repo", RefName: "master"},
+		{User: "alice:x", PeerID: "aaaaaaaa11111111", HostIp: "127.0.0.1", RepoName: "repo", RefName: "master"},
+	} {
+		err := fetchChange(change, dir, gitsync.MirrorNaming{}, nil)
+		if err == nil || !strings.Contains(err.Error(), "ref name") {
+			t.Errorf("fetching %s's %q returned %v, want a bad ref name", change.User, change.RefName, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+		t.Errorf("a ref name was run as a command")
+	}
+}
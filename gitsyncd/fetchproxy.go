@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	log "github.com/ngmoco/timber"
+	"github.com/raybejjani/gitsync/gitsync"
+	"io"
+	"net"
+	"os"
+)
+
+// gitDaemonAddr is where git daemon listens when fetches are encrypted. It is
+// kept off the network and only reached through the fetch proxy.
+const gitDaemonAddr = "127.0.0.1:9418"
+
+// proxyKeyEnvVar carries the team key to gitsyncd when git runs it as a
+// core.gitProxy command. Its presence is what puts gitsyncd in proxy mode.
+const proxyKeyEnvVar = "GITSYNC_PROXY_KEY"
+
+// serveSecureFetch accepts encrypted connections from peers' fetch proxies on
+// port and forwards them to the local git daemon. It does NOT return.
+func serveSecureFetch(port int, auth *gitsync.Authenticator) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Error("Cannot listen for encrypted fetches on %d: %s", port, err)
+		return
+	}
+	log.Info("Serving encrypted fetches on %d", port)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Error("Cannot accept encrypted fetch: %s", err)
+			continue
+		}
+
+		go func() {
+			defer conn.Close()
+
+			secure, err := gitsync.SecureServer(conn, auth.Keys())
+			if err != nil {
+				log.Warn("Rejecting fetch from %s: %s", conn.RemoteAddr(), err)
+				return
+			}
+
+			daemon, err := net.Dial("tcp", gitDaemonAddr)
+			if err != nil {
+				log.Error("Cannot reach git daemon: %s", err)
+				return
+			}
+			defer daemon.Close()
+
+			log.Debug("Proxying fetch from %s", conn.RemoteAddr())
+			go func() {
+				io.Copy(daemon, secure)
+				daemon.(*net.TCPConn).CloseWrite()
+			}()
+			io.Copy(secure, daemon)
+		}()
+	}
+}
+
+// runFetchProxy is run by git, via core.gitProxy, to connect to a peer's
+// encrypted fetch service. It shuttles the git protocol between stdin/stdout
+// and the encrypted connection.
+func runFetchProxy(host, port, hexKey string) error {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	secure, err := gitsync.SecureClient(conn, key)
+	if err != nil {
+		return err
+	}
+
+	// git signals it is done by closing our stdin, pass that on so the remote
+	// upload-pack exits
+	go func() {
+		io.Copy(secure, os.Stdin)
+		conn.(*net.TCPConn).CloseWrite()
+	}()
+	_, err = io.Copy(os.Stdout, secure)
+	return err
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	log "github.com/ngmoco/timber"
+	"github.com/raybejjani/gitsync/gitsync"
+	"os"
+	"sort"
+	"sync"
+)
+
+// history keeps the last events in a ring buffer, numbering them, and
+// optionally in a file so that they survive restarts
+type history struct {
+	sync.Mutex
+	ring    []gitsync.Event // the events, the oldest at start
+	start   int
+	n       int           // how many events ring holds
+	seq     uint64        // Seq of the last event
+	changed chan struct{} // closed, and replaced, when an event is added
+
+	file    *os.File // where events are appended, nil if not persisted
+	written int      // events in file, which is rewritten once it holds twice the ring's
+}
+
+// newHistory returns a history keeping the last size events
+func newHistory(size int) *history {
+	return &history{
+		ring:    make([]gitsync.Event, size),
+		changed: make(chan struct{})}
+}
+
+// persist loads the events kept in path, a file of JSON events, and keeps
+// those added from now on in it
+func (h *history) persist(path string) error {
+	h.Lock()
+	defer h.Unlock()
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e gitsync.Event
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Seq > h.seq {
+			h.seq = e.Seq
+			h.push(e)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		f.Close()
+		return err
+	}
+
+	h.file = f
+	return h.rewrite()
+}
+
+// push adds e to the ring, dropping the oldest event if it is full
+func (h *history) push(e gitsync.Event) {
+	if h.n < len(h.ring) {
+		h.ring[(h.start+h.n)%len(h.ring)] = e
+		h.n++
+		return
+	}
+	h.ring[h.start] = e
+	h.start = (h.start + 1) % len(h.ring)
+}
+
+// rewrite replaces the file's content with the events in the ring. It must be
+// called with the lock held.
+func (h *history) rewrite() error {
+	if err := h.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := h.file.Seek(0, 0); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(h.file)
+	enc := json.NewEncoder(w)
+	for _, e := range h.slice(0) {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	h.written = h.n
+	return w.Flush()
+}
+
+// add numbers e and adds it, waking those waiting for events. Failing to
+// write it to the file is logged, the event being kept in memory anyway.
+func (h *history) add(e gitsync.Event) gitsync.Event {
+	h.Lock()
+	defer h.Unlock()
+
+	h.seq++
+	e.Seq = h.seq
+	h.push(e)
+	close(h.changed)
+	h.changed = make(chan struct{})
+
+	if h.file != nil {
+		var err error
+		if h.written++; h.written > 2*len(h.ring) {
+			err = h.rewrite()
+		} else {
+			err = json.NewEncoder(h.file).Encode(e)
+		}
+		if err != nil {
+			log.Error("Cannot save event to %s: %s", h.file.Name(), err)
+		}
+	}
+	return e
+}
+
+// slice returns the last n events, or all of them if n is 0, oldest first. It
+// must be called with the lock held.
+func (h *history) slice(n int) []gitsync.Event {
+	if n <= 0 || n > h.n {
+		n = h.n
+	}
+	events := make([]gitsync.Event, 0, n)
+	for i := h.n - n; i < h.n; i++ {
+		events = append(events, h.ring[(h.start+i)%len(h.ring)])
+	}
+	return events
+}
+
+// last returns the last n events, or all of them if n is 0, oldest first
+func (h *history) last(n int) []gitsync.Event {
+	h.Lock()
+	defer h.Unlock()
+	return h.slice(n)
+}
+
+// since returns the events kept that came after the one numbered seq, oldest
+// first, along with a channel closed when the next event is added. All of them
+// are returned for a seq past the last event, which a client resuming from
+// before the daemon restarted without a history file, the numbering having
+// started over, asks for.
+func (h *history) since(seq uint64) ([]gitsync.Event, <-chan struct{}) {
+	h.Lock()
+	defer h.Unlock()
+	if seq > h.seq {
+		seq = 0
+	}
+	events := h.slice(0)
+	i := sort.Search(len(events), func(i int) bool { return events[i].Seq > seq })
+	return events[i:], h.changed
+}
+
+// lastSeq returns the number of the last event, 0 if there was none
+func (h *history) lastSeq() uint64 {
+	h.Lock()
+	defer h.Unlock()
+	return h.seq
+}
+
+// close stops persisting events
+func (h *history) close() error {
+	h.Lock()
+	defer h.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
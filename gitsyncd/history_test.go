@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryPersists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	h := newHistory(3)
+	if err := h.persist(file); err != nil {
+		t.Fatal(err)
+	}
+	// enough events for the file to be rewritten on the way
+	for i := 0; i < 8; i++ {
+		h.add(gitsync.Event{Kind: gitsync.EventChange, Message: fmt.Sprint(i)})
+	}
+	h.close()
+
+	h = newHistory(3)
+	if err := h.persist(file); err != nil {
+		t.Fatal(err)
+	}
+	events := h.last(0)
+	if len(events) != 3 || events[0].Seq != 6 || events[2].Message != "7" {
+		t.Errorf("reloaded %+v, want events 6 to 8", events)
+	}
+	if e := h.add(gitsync.Event{Kind: gitsync.EventReload}); e.Seq != 9 {
+		t.Errorf("next event numbered %d, want 9", e.Seq)
+	}
+	h.close()
+}
+
+func TestHistorySinceRestart(t *testing.T) {
+	h := newHistory(10)
+	for i := 0; i < 3; i++ {
+		h.add(gitsync.Event{Kind: gitsync.EventChange})
+	}
+	if events, _ := h.since(2); len(events) != 1 || events[0].Seq != 3 {
+		t.Errorf("since(2) returned %+v, want event 3", events)
+	}
+
+	// a client from before a restart, the numbering having started over, is
+	// given everything
+	if events, _ := h.since(50); len(events) != 3 || events[0].Seq != 1 {
+		t.Errorf("since(50) returned %+v, want events 1 to 3", events)
+	}
+}
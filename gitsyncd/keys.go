@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/raybejjani/gitsync/gitsync"
+	"os"
+	"text/tabwriter"
+)
+
+const keysUsage = `usage: gitsyncd [flags] keys <command>
+
+commands:
+  list                   show our key and the keys pinned for peers
+  approve <peer> [key]   trust the peer's pinned and pending keys, or the given key
+  revoke <peer> [key]    refuse all of the peer's keys, or the given key
+
+A peer is a user name, which stands for all of the user's peers, or a peer
+ID. Peer IDs and keys may be abbreviated to a prefix.`
+
+// runKeysCommand implements the keys subcommand, used to manage the keys
+// pinned for peers
+func runKeysCommand(args []string, identityPath, knownPeersPath string) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	peers, err := gitsync.LoadKnownPeers(knownPeersPath)
+	if err != nil {
+		return err
+	}
+
+	switch cmd := args[0]; {
+	case cmd == "list" && len(args) == 1:
+		id, err := gitsync.LoadIdentity(identityPath)
+		if err != nil {
+			return err
+		}
+		keys, err := peers.Keys()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Our key: %s\n\n", id)
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "PEER\tUSER\tSTATE\tKEY")
+		for _, k := range keys {
+			peerID := k.PeerID
+			if peerID == "" {
+				peerID = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", peerID, k.User, k.State, k.Key)
+		}
+		return w.Flush()
+
+	case (cmd == "approve" || cmd == "revoke") && (len(args) == 2 || len(args) == 3):
+		var (
+			peer, prefix = args[1], ""
+			n            int
+		)
+		if len(args) == 3 {
+			prefix = args[2]
+		}
+
+		if cmd == "approve" {
+			n, err = peers.Approve(peer, prefix)
+		} else {
+			n, err = peers.Revoke(peer, prefix)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%sd %d key(s) for %s\n", cmd, n, peer)
+		return nil
+	}
+
+	return errors.New(keysUsage)
+}
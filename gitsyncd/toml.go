@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The config files are written in a subset of TOML: key = value pairs, in
+// [tables] or [[arrays of tables]], whose values are strings, integers,
+// booleans or arrays of these. Inline tables, dotted keys, dates and
+// multi-line strings are not supported.
+
+// tomlTable is a table of a TOML document, mapping keys to string, int64,
+// bool or []interface{} values
+type tomlTable struct {
+	Name   string // empty for the top of the document
+	Array  bool   // given as [[Name]], one of several tables of that name
+	Values map[string]interface{}
+	Line   int // where the table starts, for errors
+}
+
+// parseTOML parses data, returning its tables in order, the top of the
+// document first
+func parseTOML(data string) ([]*tomlTable, error) {
+	var (
+		top    = &tomlTable{Values: make(map[string]interface{}), Line: 1}
+		tables = []*tomlTable{top}
+		table  = top
+		seen   = make(map[string]bool) // names of the [tables] given
+		lines  = strings.Split(data, "\n")
+	)
+
+	for n := 0; n < len(lines); n++ {
+		lineNo := n + 1
+		line := strings.TrimSpace(stripComment(lines[n]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			array := strings.HasPrefix(line, "[[")
+			name := strings.TrimPrefix(strings.TrimSuffix(line, "]"), "[")
+			if array {
+				name = strings.TrimPrefix(strings.TrimSuffix(name, "]"), "[")
+			}
+			if name = strings.TrimSpace(name); !strings.HasSuffix(line, "]") || (array && !strings.HasSuffix(line, "]]")) || !isBareKey(name) {
+				return nil, fmt.Errorf("line %d: bad table header %s", lineNo, line)
+			}
+			if !array {
+				if seen[name] {
+					return nil, fmt.Errorf("line %d: table %s given twice", lineNo, name)
+				}
+				seen[name] = true
+			}
+			table = &tomlTable{Name: name, Array: array, Values: make(map[string]interface{}), Line: lineNo}
+			tables = append(tables, table)
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key, value := strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:])
+		if !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: bad key %q", lineNo, key)
+		}
+		if _, ok := table.Values[key]; ok {
+			return nil, fmt.Errorf("line %d: %s given twice", lineNo, key)
+		}
+
+		// arrays may span lines, until their brackets balance
+		for strings.HasPrefix(value, "[") && !balanced(value) && n+1 < len(lines) {
+			n++
+			value += " " + strings.TrimSpace(stripComment(lines[n]))
+		}
+		v, rest, err := parseTOMLValue(value)
+		if rest = strings.TrimSpace(rest); err == nil && rest != "" {
+			err = fmt.Errorf("unexpected %s", rest)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		table.Values[key] = v
+	}
+	return tables, nil
+}
+
+// isBareKey reports whether s is a valid unquoted key
+func isBareKey(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// stripComment removes a trailing # comment from line, ignoring # in strings
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// balanced reports whether the brackets outside strings in s are balanced
+func balanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// parseTOMLValue parses the value at the start of s, returning what follows it
+func parseTOMLValue(s string) (v interface{}, rest string, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+
+	case s[0] == '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		str, err := strconv.Unquote(s[:end+1])
+		return str, s[end+1:], err
+
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+
+	case s[0] == '[':
+		list := []interface{}{}
+		for s = strings.TrimSpace(s[1:]); !strings.HasPrefix(s, "]"); {
+			var item interface{}
+			if item, s, err = parseTOMLValue(s); err != nil {
+				return nil, "", err
+			}
+			list = append(list, item)
+			if s = strings.TrimSpace(s); strings.HasPrefix(s, ",") {
+				s = strings.TrimSpace(s[1:])
+			} else if !strings.HasPrefix(s, "]") {
+				return nil, "", fmt.Errorf("expected , or ] in array")
+			}
+		}
+		return list, s[1:], nil
+	}
+
+	end := strings.IndexAny(s, ",] \t")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.Replace(word, "_", "", -1), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("bad value %s", word)
+	}
+	return n, rest, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc := `# a comment
+channel = "team # not a comment" # a comment
+path = 'C:\repos\gitsync'
+escaped = "say \"hi\"\tthere"
+number = 1_000
+negative = -3
+yes = true
+no = false
+empty = []
+list = ["a", 'b', "c,d"]
+nested = [[1, 2], ["three"]]
+multiline = [
+    "one", # the first
+    "two",
+]
+
+[fetch]
+users = ["alice"]
+
+[[notify]]
+command = "notify-send"
+
+[[notify]]
+command = "true"
+`
+	want := []*tomlTable{
+		{Values: map[string]interface{}{
+			"channel":   "team # not a comment",
+			"path":      `C:\repos\gitsync`,
+			"escaped":   "say \"hi\"\tthere",
+			"number":    int64(1000),
+			"negative":  int64(-3),
+			"yes":       true,
+			"no":        false,
+			"empty":     []interface{}{},
+			"list":      []interface{}{"a", "b", "c,d"},
+			"nested":    []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{"three"}},
+			"multiline": []interface{}{"one", "two"},
+		}, Line: 1},
+		{Name: "fetch", Values: map[string]interface{}{"users": []interface{}{"alice"}}, Line: 17},
+		{Name: "notify", Array: true, Values: map[string]interface{}{"command": "notify-send"}, Line: 20},
+		{Name: "notify", Array: true, Values: map[string]interface{}{"command": "true"}, Line: 23},
+	}
+
+	tables, err := parseTOML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != len(want) {
+		t.Fatalf("parsed %d tables, want %d", len(tables), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(tables[i], want[i]) {
+			t.Errorf("table %d = %+v, want %+v", i, tables[i], want[i])
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, doc := range []string{
+		"key",
+		"key =",
+		"= 1",
+		"bad key = 1",
+		"key = nope",
+		"key = 1 2",
+		"key = 1\nkey = 2",
+		`key = "unterminated`,
+		`key = 'unterminated`,
+		`key = "bad \q escape"`,
+		"key = [1, 2",
+		"key = [1 2]",
+		"key = [1,, 2]",
+		"[table",
+		"[[table]",
+		"[bad table]",
+		"[]",
+		"[table]\n[table]",
+		"[table]\nkey = 1\nkey = 2",
+	} {
+		if tables, err := parseTOML(doc); err == nil {
+			t.Errorf("parseTOML(%q) = %+v", doc, tables)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	log "github.com/ngmoco/timber"
@@ -8,8 +9,31 @@ import (
 	"github.com/raybejjani/gitsync/gitsyncd/webcontent"
 	"golang.org/x/net/websocket"
 	"html/template"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Websocket clients are sent a ping every pingPeriod, and dropped when nothing,
+// not even a pong, is read from them for pongWait or when writing to them
+// takes longer than writeWait
+const (
+	pingPeriod = 30 * time.Second
+	pongWait   = 2 * pingPeriod
+	writeWait  = 10 * time.Second
+)
+
+// clientQueue is how many changes may wait to be sent to a websocket client
+// before it is handled per the slow client policy
+const clientQueue = 64
+
+// What to do with websocket clients whose queue is full, see -slowclients
+const (
+	slowDrop       = "drop"       // drop the changes that do not fit
+	slowDisconnect = "disconnect" // close the connection, for the client to resume with ?since=
 )
 
 // makeWebsocketName composes an identifier for a websocket client
@@ -17,18 +41,64 @@ func makeWebsocketName(ws *websocket.Conn) string {
 	return fmt.Sprintf("[%p]%s", ws, ws.RemoteAddr())
 }
 
+// webConn is a connection to the web server. Once it carries a websocket its
+// reads time out when the client stays quiet for too long, so that clients
+// that went away without closing the connection are noticed.
+type webConn struct {
+	net.Conn
+	idle int64 // how long reads may wait, as a time.Duration, 0 for ever
+}
+
+func (c *webConn) Read(b []byte) (int, error) {
+	if idle := time.Duration(atomic.LoadInt64(&c.idle)); idle > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(idle))
+	}
+	return c.Conn.Read(b)
+}
+
+// setIdleTimeout makes reads fail once nothing was read for idle
+func (c *webConn) setIdleTimeout(idle time.Duration) {
+	atomic.StoreInt64(&c.idle, int64(idle))
+}
+
+// webListener hands out the connections it accepts as webConns
+type webListener struct {
+	net.Listener
+}
+
+func (l webListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &webConn{Conn: c}, nil
+}
+
+// webConnKey is the request context key of the request's webConn
+type webConnKey struct{}
+
+// webClient is a websocket client and the queue of changes it is yet to be
+// sent
+type webClient struct {
+	ws      *websocket.Conn
+	changes chan gitsync.StreamedChange // at most clientQueue changes
+	slow    chan struct{}               // closed once the client is to be disconnected for being slow
+	dropped int                         // changes dropped in a row, touched by the distributor only
+}
+
 // clientSet is a set of websocket clients. It allows us to distribute events to
 // them and manage membership
 type clientSet struct {
-	sync.RWMutex                                            // lock the set
-	clients      map[*websocket.Conn]chan gitsync.GitChange // set of websocket clients
+	sync.RWMutex                                // lock the set
+	clients      map[*websocket.Conn]*webClient // set of websocket clients
+	policy       string                         // slowDrop or slowDisconnect
 }
 
 // Add Client adds a client to the set, it does not check for prior membership
-func (cs *clientSet) AddClient(ws *websocket.Conn, ch chan gitsync.GitChange) {
+func (cs *clientSet) AddClient(client *webClient) {
 	cs.Lock()
 	defer cs.Unlock()
-	cs.clients[ws] = ch
+	cs.clients[client.ws] = client
 }
 
 // RemoveClient removes the client from the set
@@ -38,46 +108,174 @@ func (cs *clientSet) RemoveClient(ws *websocket.Conn) {
 	delete(cs.clients, ws)
 }
 
-// distributeEvent will sent a copy of the event to all clients
-func (cs *clientSet) distributeEvent(event gitsync.GitChange) {
+// distributeEvent will queue a copy of the event for all clients. It never
+// blocks: clients whose queue is full miss the event or are disconnected, per
+// the set's policy.
+func (cs *clientSet) distributeEvent(event gitsync.StreamedChange) {
 	cs.RLock()
 	defer cs.RUnlock()
-	for _, clientChannel := range cs.clients {
-		clientChannel <- event
+	for _, client := range cs.clients {
+		select {
+		case client.changes <- event:
+			if client.dropped > 0 {
+				log.Warn("%s: Dropped %d changes the client was too slow for", makeWebsocketName(client.ws), client.dropped)
+				client.dropped = 0
+			}
+			continue
+		default:
+		}
+
+		if cs.policy == slowDrop {
+			client.dropped++
+			continue
+		}
+		select {
+		case <-client.slow:
+		default:
+			log.Warn("%s: Disconnecting the client, which fell %d changes behind", makeWebsocketName(client.ws), clientQueue)
+			close(client.slow)
+		}
+	}
+}
+
+// distribute follows the history and sends the changes recorded in it from now
+// on to all listening clients. Reading them from the history, rather than
+// having them handed over, means none is dropped when clients are slow, unless
+// the history itself moves on without them.
+func (cs *clientSet) distribute(h *history) {
+	seq := h.lastSeq()
+	for {
+		events, changed := h.since(seq)
+		if len(events) == 0 {
+			<-changed
+			continue
+		}
+		if events[0].Seq > seq+1 {
+			log.Warn("Web clients missed %d events, the history moved on", events[0].Seq-seq-1)
+		}
+		for _, event := range events {
+			seq = event.Seq
+			if event.Kind == gitsync.EventChange && event.Change != nil {
+				cs.distributeEvent(gitsync.StreamedChange{Seq: event.Seq, GitChange: *event.Change})
+			}
+		}
 	}
 }
 
-// distribute will loop on incoming events and send them to all listening
-// clients
-func (cs *clientSet) distribute(events chan *gitsync.GitChange) {
-	for event := range events {
-		cs.distributeEvent(*event)
+// writeChange sends change to the client
+func writeChange(ws *websocket.Conn, change gitsync.StreamedChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		log.Info("%s: Cannot marshall event data %+v. %s", makeWebsocketName(ws), change, err)
+		return nil
+	}
+	ws.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := ws.Write(data); err != nil {
+		log.Error("%s: Cannot write out event: %s", makeWebsocketName(ws), err)
+		return err
 	}
+	log.Info("%s: Wrote out data", makeWebsocketName(ws))
+	return nil
+}
+
+// writePing sends the client a ping, which it answers with a pong
+func writePing(ws *websocket.Conn) error {
+	ws.SetWriteDeadline(time.Now().Add(writeWait))
+	ws.PayloadType = websocket.PingFrame
+	defer func() { ws.PayloadType = websocket.TextFrame }()
+	_, err := ws.Write(nil)
+	return err
 }
 
-// handleGitChangeWebClient will build and register a channel to revieve events
-// on from a distributor in clientSet. It will exit on error but will consume
-// one more event after a client disconnects before it does so.
-func handleGitChangeWebClient(cs *clientSet, ws *websocket.Conn) {
-	var events = make(chan gitsync.GitChange)
+// handleGitChangeWebClient registers a client with a queue to receive events
+// on from a distributor in clientSet, and sends them on until the connection
+// fails, the client goes quiet or it is disconnected for being too slow.
+// The changes kept in h are replayed first, only those after the one numbered
+// by the request's ?since=<seq> if given, so that clients can resume where
+// they left off. Only events matching the gitsync.ChangeFilter in the
+// request's query are sent to the client.
+func handleGitChangeWebClient(cs *clientSet, h *history, ws *websocket.Conn) {
+	var (
+		client = &webClient{
+			ws:      ws,
+			changes: make(chan gitsync.StreamedChange, clientQueue),
+			slow:    make(chan struct{})}
+		query  = ws.Request().URL.Query()
+		filter = gitsync.ParseChangeFilter(query)
+		seq    uint64 // Seq of the last event sent
+	)
 
 	log.Info("Begin handling %s", makeWebsocketName(ws))
 	defer log.Info("End handling %s", makeWebsocketName(ws))
+	defer ws.Close()
+
+	if since := query.Get("since"); since != "" {
+		var err error
+		if seq, err = strconv.ParseUint(since, 10, 64); err != nil {
+			log.Info("%s: Bad since %s", makeWebsocketName(ws), since)
+			return
+		}
+	}
 
-	cs.AddClient(ws, events)
+	// the pongs answering our pings keep the connection from timing out. They
+	// are only read while reading from the client, which also tells us when it
+	// closes the connection.
+	if conn, ok := ws.Request().Context().Value(webConnKey{}).(*webConn); ok {
+		conn.setIdleTimeout(pongWait)
+	}
+	closed := make(chan error, 1)
+	go func() {
+		var buf [512]byte
+		for {
+			if _, err := ws.Read(buf[:]); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	// join before replaying so that no event falls between the two, those
+	// queued meanwhile being skipped below
+	cs.AddClient(client)
 	defer cs.RemoveClient(ws)
 
-	for event := range events {
-		if data, err := json.Marshal(event); err != nil {
-			log.Info("%s: Cannot marshall event data %+v. %s", makeWebsocketName(ws), event, err)
-		} else {
-			if _, err := ws.Write(data); err != nil {
-				ws.Close()
-				log.Error("%s: Cannot write out event: %s", makeWebsocketName(ws), err)
+	// a client resuming from before the daemon restarted is sent everything,
+	// the numbering having started over
+	if seq > h.lastSeq() {
+		seq = 0
+	}
+	replay, _ := h.since(seq)
+	for _, event := range replay {
+		seq = event.Seq
+		if event.Kind != gitsync.EventChange || event.Change == nil || !filter.Match(*event.Change) {
+			continue
+		}
+		if err := writeChange(ws, gitsync.StreamedChange{Seq: event.Seq, GitChange: *event.Change}); err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case event := <-client.changes:
+			if event.Seq <= seq || !filter.Match(event.GitChange) {
+				continue
+			}
+			if err := writeChange(ws, event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := writePing(ws); err != nil {
+				log.Info("%s: Cannot ping: %s", makeWebsocketName(ws), err)
 				return
-			} else {
-				log.Info("%s: Wrote out data", makeWebsocketName(ws))
 			}
+		case err := <-closed:
+			log.Info("%s: Connection closed: %s", makeWebsocketName(ws), err)
+			return
+		case <-client.slow:
+			return
 		}
 	}
 }
@@ -89,33 +287,55 @@ func renderTemplate(w http.ResponseWriter, tmplPath string) {
 	}
 }
 
-// serveWeb starts a webserver that can serve a page and websocket events as
-// they are seen.
+// serveWeb starts a webserver on host and port that can serve a page,
+// websocket events as they are recorded in ctl's history and, under /api, the
+// daemon's state as kept by ctl. Websocket clients too slow to keep up are handled per slowPolicy.
 // It is expected to be run only once and uses the http package global request
 // router. It does NOT return.
-func serveWeb(port uint16, events chan *gitsync.GitChange) {
+func serveWeb(host string, port uint16, ctl *controller, slowPolicy string) {
 	// the container for websocket clients, passed into every websocket handler
 	// below
 	var cs = clientSet{
-		clients: make(map[*websocket.Conn]chan gitsync.GitChange)}
+		clients: make(map[*websocket.Conn]*webClient),
+		policy:  slowPolicy}
+
+	// what the page templates are executed with
+	page := webcontent.Context{
+		Port:    port,
+		User:    ctl.self.User,
+		Repos:   ctl.Repos(),
+		Version: version}
 
 	// Handle any static files (JS/CSS files)
-	if handler, err := webcontent.NewMapHandler(webcontent.Paths); err != nil {
+	if handler, err := webcontent.NewMapHandler(webcontent.Paths, page); err != nil {
 		log.Error("Error building templates for web content: %s", err)
 	} else {
 		log.Debug("Registering / handler")
 		http.Handle("/", handler)
 	}
 
+	http.Handle("/api/", apiHandler(ctl))
+
 	// Events endpoint
 	// Note: we wrap the handler in a closure to pass in the clientSet
 	http.Handle("/events", websocket.Handler(func(ws *websocket.Conn) {
-		handleGitChangeWebClient(&cs, ws)
+		handleGitChangeWebClient(&cs, ctl.history, ws)
 	}))
 
-	log.Info("Attempting to spawn webserver on %d", port)
-	go cs.distribute(events)
-	if err := http.ListenAndServe(fmt.Sprintf(":%v", port), nil); err != nil {
-		log.Error("Error listening on %d: %s", port, err)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	log.Info("Attempting to spawn webserver on %s", addr)
+	go cs.distribute(ctl.history)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Error("Error listening on %s: %s", addr, err)
+		return
+	}
+	server := &http.Server{
+		// let websocket handlers find their connection, see webConn
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, webConnKey{}, c)
+		}}
+	if err := server.Serve(webListener{listener}); err != nil {
+		log.Error("Error serving on %s: %s", addr, err)
 	}
 }
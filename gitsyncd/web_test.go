@@ -0,0 +1,182 @@
+package main
+
+import (
+	"github.com/raybejjani/gitsync/gitsync"
+	"golang.org/x/net/websocket"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowEvents is how many changes the stalled client is sent, well beyond
+// what its queue and the connection's buffers can hold
+const slowEvents = 1000
+
+// startEvents serves the /events websocket with slow clients handled per
+// policy, returning the clients, the history whose changes are sent and its
+// URL
+func startEvents(t *testing.T, policy string) (*clientSet, *history, string) {
+	cs := &clientSet{clients: make(map[*websocket.Conn]*webClient), policy: policy}
+	h := newHistory(2 * slowEvents)
+	go cs.distribute(h)
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		handleGitChangeWebClient(cs, h, ws)
+	}))
+	t.Cleanup(server.Close)
+	return cs, h, "ws" + strings.TrimPrefix(server.URL, "http") + "/events"
+}
+
+// dialEvents connects to the websocket at url
+func dialEvents(t *testing.T, url string) *websocket.Conn {
+	ws, err := websocket.Dial(url, "", "http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// receive reads the next change sent to ws
+func receive(ws *websocket.Conn) (gitsync.StreamedChange, error) {
+	var change gitsync.StreamedChange
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	err := websocket.JSON.Receive(ws, &change)
+	return change, err
+}
+
+// sendLockstep records a change and waits for fast to be sent it, so that
+// fast never falls behind. Changes are large to fill the connections'
+// buffers.
+func sendLockstep(t *testing.T, h *history, fast *websocket.Conn) uint64 {
+	change := gitsync.GitChange{User: "alice", PeerID: "aaaaaaaa11111111", RefName: "topic", Current: strings.Repeat("c", 16<<10)}
+	seq := h.add(gitsync.Event{Time: time.Now(), Kind: gitsync.EventChange, Change: &change}).Seq
+	got, err := receive(fast)
+	if err != nil {
+		t.Fatalf("fast client got no change %d: %s", seq, err)
+	}
+	if got.Seq != seq {
+		t.Fatalf("fast client got change %d, want %d", got.Seq, seq)
+	}
+	return seq
+}
+
+// connectClients connects a client that is not read from until the test says
+// so and one that keeps up, returning once cs sends changes to both
+func connectClients(t *testing.T, cs *clientSet, url string) (stalled, fast *websocket.Conn) {
+	stalled = dialEvents(t, url)
+	fast = dialEvents(t, url)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		cs.RLock()
+		joined := len(cs.clients)
+		cs.RUnlock()
+		if joined == 2 {
+			return stalled, fast
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of 2 clients joined", joined)
+		}
+	}
+}
+
+func TestWebSlowClientDisconnected(t *testing.T) {
+	t.Parallel()
+	cs, h, url := startEvents(t, slowDisconnect)
+	stalled, fast := connectClients(t, cs, url)
+	for i := 0; i < slowEvents; i++ {
+		sendLockstep(t, h, fast)
+	}
+
+	// the stalled client is sent what was written before it fell behind, in
+	// order, then the connection is closed
+	var seq uint64
+	for {
+		change, err := receive(stalled)
+		if err != nil {
+			break
+		}
+		if change.Seq != seq+1 {
+			t.Fatalf("stalled client got change %d after %d", change.Seq, seq)
+		}
+		seq = change.Seq
+	}
+	if seq == 0 || seq >= slowEvents {
+		t.Errorf("stalled client got %d of %d changes before being disconnected", seq, slowEvents)
+	}
+
+	// which does not affect the other client
+	sendLockstep(t, h, fast)
+}
+
+func TestWebSlowClientDropped(t *testing.T) {
+	t.Parallel()
+	cs, h, url := startEvents(t, slowDrop)
+	stalled, fast := connectClients(t, cs, url)
+	for i := 0; i < slowEvents; i++ {
+		sendLockstep(t, h, fast)
+	}
+
+	// the stalled client stays connected, missing some changes, and is sent
+	// those that come once it caught up
+	received := make(chan gitsync.StreamedChange, 2*slowEvents)
+	go func() {
+		defer close(received)
+		for {
+			change, err := receive(stalled)
+			if err != nil {
+				return
+			}
+			received <- change
+		}
+	}()
+	var (
+		seq, count uint64
+		last       = uint64(slowEvents)
+	)
+	for seq <= slowEvents {
+		select {
+		case change, ok := <-received:
+			if !ok {
+				t.Fatalf("stalled client was disconnected after change %d", seq)
+			}
+			if change.Seq <= seq {
+				t.Fatalf("stalled client got change %d after %d", change.Seq, seq)
+			}
+			seq = change.Seq
+			count++
+		case <-time.After(10 * time.Millisecond):
+			if last >= 2*slowEvents-1 {
+				t.Fatalf("stalled client got no change after %d", seq)
+			}
+			last = sendLockstep(t, h, fast)
+		}
+	}
+	if count >= seq {
+		t.Errorf("stalled client got all %d changes, none dropped", count)
+	}
+}
+
+func TestWebResumeAfterRestart(t *testing.T) {
+	t.Parallel()
+	_, h, url := startEvents(t, slowDisconnect)
+	change := gitsync.GitChange{User: "alice", PeerID: "aaaaaaaa11111111", RefName: "topic"}
+	for i := 0; i < 2; i++ {
+		h.add(gitsync.Event{Time: time.Now(), Kind: gitsync.EventChange, Change: &change})
+	}
+
+	// a client that got further before the daemon restarted is sent the
+	// changes numbered since, then the new ones
+	ws := dialEvents(t, url+"?since=50")
+	for want := uint64(1); want <= 3; want++ {
+		if want == 3 {
+			h.add(gitsync.Event{Time: time.Now(), Kind: gitsync.EventChange, Change: &change})
+		}
+		got, err := receive(ws)
+		if err != nil {
+			t.Fatalf("change %d not received: %s", want, err)
+		}
+		if got.Seq != want {
+			t.Fatalf("received change %d, want %d", got.Seq, want)
+		}
+	}
+}
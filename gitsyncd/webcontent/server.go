@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	log "github.com/ngmoco/timber"
+	"github.com/raybejjani/gitsync/gitsync"
 	"html/template"
 	"io"
 	"mime"
@@ -13,15 +14,25 @@ import (
 	"strings"
 )
 
+// Context describes the daemon to the templates, which are executed with it
+type Context struct {
+	Port    uint16 // the web server's
+	User    string
+	Repos   []gitsync.RepoInfo
+	Version string
+}
+
 type mapHandler struct {
 	templatePaths map[string]*template.Template
 	staticPaths   map[string][]byte
+	context       Context
 }
 
-func NewMapHandler(paths map[string]string) (handler http.Handler, err error) {
+func NewMapHandler(paths map[string]string, context Context) (handler http.Handler, err error) {
 	h := mapHandler{
 		templatePaths: make(map[string]*template.Template),
-		staticPaths:   make(map[string][]byte)}
+		staticPaths:   make(map[string][]byte),
+		context:       context}
 
 	for path, data := range paths {
 		log.Debug("Parsing in web asset %s", path)
@@ -31,7 +42,8 @@ func NewMapHandler(paths map[string]string) (handler http.Handler, err error) {
 		}
 
 		switch {
-		case strings.HasPrefix(path, "templates/"):
+		// the root is a copy of the index, which is a template
+		case path == "/" || strings.HasPrefix(path, "/templates/"):
 			log.Debug("Parsing in templated web asset glob %s", path)
 			t, err := template.New(path).Parse(string(dataBytes))
 			if err != nil {
@@ -55,12 +67,15 @@ func (m mapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ctype = mime.TypeByExtension(filepath.Ext(upath))
 	)
 
-	w.Header().Set("Content-Type", ctype)
+	// without an extension, as for /, the type is sniffed from the content
+	if ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
 
 	log.Debug("Handling %s", upath)
 	if t, found := m.templatePaths[upath]; found {
 		log.Debug("Found data for %s of type %s", upath, ctype)
-		if err := t.Execute(w, nil); err != nil {
+		if err := t.Execute(w, m.context); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	} else if t, found := m.staticPaths[upath]; found {
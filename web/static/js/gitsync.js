@@ -1,24 +1,240 @@
-function currentTimestamp() {
-    function pad(n){return n < 10 ? '0' + n : n;}
-    var date = new Date();
-    return pad(date.getHours()) + ':'
-        + pad(date.getMinutes());
+// The gitsync dashboard. The peers, branches and activity are read from the
+// daemon's /api and kept up to date by following its /events websocket.
+
+var activeTime = 60 * 60 * 1000; // peers heard from since are shown as active
+var maxTimeline = 500;           // events kept in the timeline
+var retryDelay = 5000;           // before reconnecting to the websocket
+
+var timeline = [];  // events, newest first
+var seen = {};      // Seq of the events in the timeline -> true
+var apiSeq = 0;     // Seq of the last event read from the API
+var lastSeq = 0;    // Seq of the last change sent on the websocket, or apiSeq
+var refreshing = null;
+
+// age formats how long ago the time t, as sent by the daemon, was
+function age(t) {
+    var secs = Math.max(0, Math.round((Date.now() - new Date(t).getTime()) / 1000));
+    if (secs < 60) {
+        return secs + 's ago';
+    } else if (secs < 3600) {
+        return Math.floor(secs / 60) + 'm ago';
+    } else if (secs < 86400) {
+        return Math.floor(secs / 3600) + 'h ago';
+    }
+    return Math.floor(secs / 86400) + 'd ago';
 }
 
-$(function() {
-    var ws = new WebSocket('ws://localhost:12345/events');
-    ws.onmessage = function (evt) {
-        var gitChange = JSON.parse(evt.data);
-        var user = gitChange['User'];
-        var refName = gitChange['RefName'];
-        var checkedOut = gitChange['CheckedOut'];
-        var timestamp = currentTimestamp();
-        if (checkedOut) {
-            $('<div class="status">' + user + ' modified ' + 
-              refName + ' <span class="timestamp">' + timestamp + '</span></div>')
-                .hide()
-                .appendTo('.container')
-                .fadeIn(1000);
+function shortPeerID(id) {
+    return id ? id.substring(0, 8) : '';
+}
+
+function shortHash(hash) {
+    return hash ? hash.substring(0, 7) : '';
+}
+
+// row builds a table row holding cells, each a string or a jQuery element
+function row(cells) {
+    var tr = $('<tr>');
+    $.each(cells, function(i, cell) {
+        $('<td>').append(typeof cell === 'string' ? document.createTextNode(cell) : cell)
+            .appendTo(tr);
+    });
+    return tr;
+}
+
+function renderPeers(peers) {
+    var body = $('#peers tbody').empty();
+    if (peers.length === 0) {
+        body.append('<tr class="empty"><td colspan="4">No peers heard from yet</td></tr>');
+        return;
+    }
+    $.each(peers, function(i, peer) {
+        var user = $('<span>').text(peer.User + ' ')
+            .append($('<small class="peerid">').text(shortPeerID(peer.PeerID)));
+        if (peer.KeyMismatch) {
+            user.append(' <span class="label label-warning">key not approved</span>');
+        }
+        var tr = row([user, peer.HostIp, peer.CheckedOut || '', age(peer.LastSeen)]);
+        if (Date.now() - new Date(peer.LastSeen).getTime() > activeTime) {
+            tr.addClass('inactive');
+        }
+        tr.appendTo(body);
+    });
+}
+
+function renderBranches(mirrors) {
+    var body = $('#branches tbody').empty();
+    if (mirrors.length === 0) {
+        body.append('<tr class="empty"><td colspan="4">No branches mirrored yet</td></tr>');
+        return;
+    }
+    $.each(mirrors, function(i, m) {
+        var user = m.Change ? m.Change.User : '',
+            branch = m.Change ? m.Change.RefName : m.Branch,
+            tip = $('<span>'),
+            when = '';
+        if (m.Tip) {
+            tip.append($('<code>').text(shortHash(m.Tip.Hash)))
+                .append(document.createTextNode(' ' + m.Tip.Subject));
+            when = age(m.Tip.Time);
+        } else if (m.FetchError) {
+            tip.addClass('text-danger').text('not fetched: ' + m.FetchError);
+        } else {
+            tip.addClass('text-muted').text('not fetched');
+        }
+        row([user, branch, tip, when]).attr('title', m.Branch).appendTo(body);
+    });
+}
+
+// matches tells whether event passes the timeline's filter
+function matches(event) {
+    var text = $('#filter-text').val().toLowerCase(),
+        kind = $('#filter-kind').val(),
+        change = event.Change;
+    if (kind && event.Kind !== kind) {
+        return false;
+    }
+    if ($('#filter-checkedout').is(':checked') && !(change && change.CheckedOut)) {
+        return false;
+    }
+    if (text && event.Message.toLowerCase().indexOf(text) < 0 &&
+        !(change && (change.User.toLowerCase().indexOf(text) >= 0 ||
+                     change.RefName.toLowerCase().indexOf(text) >= 0))) {
+        return false;
+    }
+    return true;
+}
+
+function renderTimeline() {
+    var list = $('#timeline').empty();
+    $.each(timeline, function(i, event) {
+        if (!matches(event)) {
+            return;
+        }
+        var item = $('<li class="list-group-item">').addClass('event-' + event.Kind)
+            .append($('<span class="kind label">').text(event.Kind))
+            .append(document.createTextNode(' ' + event.Message + ' '))
+            .append($('<span class="timestamp">').text(age(event.Time))
+                    .attr('title', new Date(event.Time).toLocaleString()));
+        item.appendTo(list);
+    });
+    if (list.children().length === 0) {
+        list.append('<li class="list-group-item empty">Nothing to show</li>');
+    }
+}
+
+// addEvents adds events to the timeline, skipping those already in it
+function addEvents(events) {
+    $.each(events, function(i, event) {
+        if (!seen[event.Seq]) {
+            seen[event.Seq] = true;
+            timeline.push(event);
         }
+    });
+    timeline.sort(function(a, b) { return b.Seq - a.Seq; });
+    $.each(timeline.splice(maxTimeline), function(i, event) {
+        delete seen[event.Seq];
+    });
+    renderTimeline();
+}
+
+// resetTimeline forgets the events seen, for when the daemon restarted and
+// numbers its events from 1 again
+function resetTimeline() {
+    timeline = [];
+    seen = {};
+    apiSeq = 0;
+    lastSeq = 0;
+}
+
+// addAPIEvents adds events read from the API, which has all kinds of events
+// where the websocket only sends changes. since is the Seq they were asked to
+// come after: the daemon only sends older ones when it restarted.
+function addAPIEvents(events, since) {
+    if (since && events.length > 0 && events[0].Seq <= since) {
+        resetTimeline();
+    }
+    $.each(events, function(i, event) {
+        apiSeq = Math.max(apiSeq, event.Seq);
+        lastSeq = Math.max(lastSeq, event.Seq);
+    });
+    addEvents(events);
+}
+
+// refresh reloads the peers and branches, and the events not yet seen
+function refresh() {
+    var since = apiSeq;
+    $.getJSON('api/peers', renderPeers);
+    $.getJSON('api/branches', renderBranches);
+    $.getJSON('api/events?since=' + since, function(events) {
+        addAPIEvents(events, since);
+    });
+}
+
+// scheduleRefresh refreshes shortly, once for changes coming close together
+function scheduleRefresh() {
+    if (refreshing === null) {
+        refreshing = setTimeout(function() {
+            refreshing = null;
+            refresh();
+        }, 500);
+    }
+}
+
+// eventsURL returns the URL of the websocket, next to the page so that it
+// works on any port and behind a reverse proxy
+function eventsURL() {
+    var url = new URL('events', document.baseURI);
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+    return url.href;
+}
+
+function setConnection(text, kind) {
+    $('#connection').text(text).attr('class', 'label label-' + kind);
+}
+
+// follow follows the websocket, from the last event seen, reconnecting when
+// the connection is lost
+function follow() {
+    var ws = new WebSocket(eventsURL() + '?since=' + lastSeq);
+    ws.onopen = function() {
+        setConnection('live', 'success');
+    };
+    ws.onmessage = function(evt) {
+        var change = JSON.parse(evt.data);
+        // only changes after lastSeq are sent, unless the daemon restarted
+        if (change.Seq <= lastSeq) {
+            resetTimeline();
+        }
+        lastSeq = Math.max(lastSeq, change.Seq);
+        addEvents([{
+            Seq: change.Seq,
+            Time: new Date().toISOString(),
+            Kind: 'change',
+            Message: change.User + ' moved ' + change.RefName + ' to ' + change.Current,
+            Change: change
+        }]);
+        // fetches and the peers' state are only in the API
+        scheduleRefresh();
+    };
+    ws.onclose = function() {
+        setConnection('disconnected', 'danger');
+        setTimeout(follow, retryDelay);
     };
-});
\ No newline at end of file
+}
+
+$(function() {
+    $('#filter').on('submit', function() { return false; });
+    $('#filter-text').on('input', renderTimeline);
+    $('#filter-kind, #filter-checkedout').on('change', renderTimeline);
+
+    $.getJSON('api/events', function(events) {
+        addAPIEvents(events);
+        follow();
+    });
+    $.getJSON('api/peers', renderPeers);
+    $.getJSON('api/branches', renderBranches);
+
+    // keep the ages current
+    setInterval(refresh, 30000);
+});